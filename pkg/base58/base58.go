@@ -8,13 +8,92 @@
 // Original author: Philip Taffet <phtaffet@jumptrading.com>
 package base58
 
+//go:generate go run ./internal/gentables/cmd/gentables -o tables.go
+
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
 )
 
 var ErrEncode = errors.New("base58 encoding error")
 
+// ErrWrongLength is returned when decoding input of a length that
+// cannot encode a value of the requested size.
+var ErrWrongLength = errors.New("base58 input has wrong length")
+
+// LengthError describes input of the wrong length for a 32-byte value.
+// It matches ErrWrongLength with errors.Is.
+type LengthError struct {
+	Len      int // length of the input
+	Min, Max int // accepted lengths
+}
+
+// lengthHintMax is the largest distance from the accepted range for
+// which LengthError suggests a truncated or extended copy.
+const lengthHintMax = 2
+
+func (e *LengthError) Error() string {
+	msg := fmt.Sprintf("%v: %d characters, expected %d–%d", ErrWrongLength, e.Len, e.Min, e.Max)
+	off := e.Min - e.Len
+	if e.Len > e.Max {
+		off = e.Len - e.Max
+	}
+	if off <= lengthHintMax {
+		msg += fmt.Sprintf(" (off by %d)", off)
+	}
+	return msg
+}
+
+func (e *LengthError) Is(target error) bool {
+	return target == ErrWrongLength
+}
+
+// MaxDecodeInputLen is the length beyond which all decoders reject input
+// up front, before inspecting its characters.  It bounds the work done
+// on untrusted input regardless of how the input is routed.
+var MaxDecodeInputLen = 128
+
+// inputLenError32 is like checkInputLen for a 32-byte value, but
+// returns a LengthError.  It is kept apart from checkInputLen so that
+// the bool returning decoders never allocate.
+func inputLenError32(n int) error {
+	if checkInputLen(n, 32, 44) != nil {
+		return &LengthError{Len: n, Min: 32, Max: 44}
+	}
+	return nil
+}
+
+// OnDecodeError, if non-nil, is called with the error returned by any
+// failing decoder that reports errors, such as ParsePubkey or
+// Decode32Full, for example to count failures by reason.  Decoders
+// returning a bool do not call it.
+//
+// It must be set before decoding starts, typically during program
+// initialization, and must be safe for concurrent use.
+var OnDecodeError func(reason error)
+
+// decodeFailed reports err to OnDecodeError and returns it.
+func decodeFailed(err error) error {
+	if OnDecodeError != nil {
+		OnDecodeError(err)
+	}
+	return err
+}
+
+// checkInputLen returns ErrWrongLength if n exceeds MaxDecodeInputLen or
+// lies outside [min, max].
+func checkInputLen(n, min, max int) error {
+	if n > MaxDecodeInputLen || n < min || n > max {
+		return ErrWrongLength
+	}
+	return nil
+}
+
 // alphabet maps [0, 58) to the base58 character.
 const alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
 
@@ -36,49 +115,38 @@ const invalidChar uint8 = 0xFF
 const inverseLUTOffset uint8 = '1'
 const inverseLUTSentinel uint8 = 1 + 'z' - inverseLUTOffset
 
-// encTable32 contains the unique values less than 58^5 such that:
-//
-//	2^(32*(7-j)) = sum_k table[j][k]*58^(5*(7-k))
-//
-// The second dimension of this table is actually ceil(log_(58^5)
-// (2^(32*7)), but that's almost always 8
-var encTable32 = [9][8]uint32{
-	{513735, 77223048, 437087610, 300156666, 605448490, 214625350, 141436834, 379377856},
-	{0, 78508, 646269101, 118408823, 91512303, 209184527, 413102373, 153715680},
-	{0, 0, 11997, 486083817, 3737691, 294005210, 247894721, 289024608},
-	{0, 0, 0, 1833, 324463681, 385795061, 551597588, 21339008},
-	{0, 0, 0, 0, 280, 127692781, 389432875, 357132832},
-	{0, 0, 0, 0, 0, 42, 537767569, 410450016},
-	{0, 0, 0, 0, 0, 0, 6, 356826688},
-	{0, 0, 0, 0, 0, 0, 0, 1},
-}
-
-// decTable32 contains the unique values less than 2^32 such that:
-//
-//	58^(5*(8-j)) = sum_k table[j][k]*2^(32*(7-k))
-var decTable32 = [9][8]uint32{
-	{1277, 2650397687, 3801011509, 2074386530, 3248244966, 687255411, 2959155456, 0},
-	{0, 8360, 1184754854, 3047609191, 3418394749, 132556120, 1199103528, 0},
-	{0, 0, 54706, 2996985344, 1834629191, 3964963911, 485140318, 1073741824},
-	{0, 0, 0, 357981, 1476998812, 3337178590, 1483338760, 4194304000},
-	{0, 0, 0, 0, 2342503, 3052466824, 2595180627, 17825792},
-	{0, 0, 0, 0, 0, 15328518, 1933902296, 4063920128},
-	{0, 0, 0, 0, 0, 0, 100304420, 3355157504},
-	{0, 0, 0, 0, 0, 0, 0, 656356768},
-	{0, 0, 0, 0, 0, 0, 0, 1},
-}
-
 func Encode32(out *[44]byte, in [32]byte) uint {
-	const raw58sz = 45
+	n := digits32(out, in)
+	for i := uint(0); i < n; i++ {
+		out[i] = alphabet[out[i]]
+	}
+	return n
+}
 
-	// Count leading zeros (needed for final output)
-	var inLeading0s uint
-	for i := range in {
-		if in[i] != 0 {
-			break
-		}
-		inLeading0s++
+// Encode32Runes returns the base58 encoding of in as a rune slice,
+// widening each character directly without an intermediate string.
+func Encode32Runes(in [32]byte) []rune {
+	var digits [44]byte
+	n := digits32(&digits, in)
+	out := make([]rune, n)
+	for i := range out {
+		out[i] = rune(alphabet[digits[i]])
 	}
+	return out
+}
+
+// Digits32 returns the base58 digits (0-57) of a 32-byte value, most
+// significant first.  Each leading zero byte is represented by a zero
+// digit, matching the leading '1' characters of the encoding.
+func Digits32(in [32]byte) []byte {
+	var out [44]byte
+	n := digits32(&out, in)
+	return append([]byte(nil), out[:n]...)
+}
+
+// digits32 writes the base58 digits of in to out and returns their count.
+func digits32(out *[44]byte, in [32]byte) uint {
+	const raw58sz = 45
 
 	// X = sum_i bytes[i] * 2^(8*(32-1-i))
 
@@ -147,13 +215,33 @@ func Encode32(out *[44]byte, in [32]byte) uint {
 	// rawBase58 actually has at least inLeading0s, so we'll do this
 	// by skipping the first few leading zeros in rawBase58.
 
+	// Fast path for the common case of no leading zero bytes: then
+	// 58^42 < 2^248 <= X < 2^256 < 58^44, so X has 43 or 44 digits and
+	// rawBase58 starts with exactly two or one zeros.
+	if in[0] != 0 {
+		skip := uint(1)
+		if rawBase58[1] == 0 {
+			skip = 2
+		}
+		copy(out[:], rawBase58[skip:])
+		return raw58sz - skip
+	}
+
+	// Count leading zeros
+	var inLeading0s uint
+	for i := range in {
+		if in[i] != 0 {
+			break
+		}
+		inLeading0s++
+	}
+
 	var rawLeading0s uint
 	for rawLeading0s = 0; rawLeading0s < raw58sz; rawLeading0s++ {
 		if rawBase58[rawLeading0s] != 0 {
 			break
 		}
 	}
-
 	// It's not immediately obvious that rawLeading0s >= inLeading0s,
 	// but it's true.  In base b, X has floor(log_b X)+1 digits.  That
 	// means inLeading0s = N-1-floor(log_256 X) and rawLeading0s =
@@ -174,44 +262,61 @@ func Encode32(out *[44]byte, in [32]byte) uint {
 	// Regardless, rawLeading0s - inLeading0s >= 0.
 
 	skip := rawLeading0s - inLeading0s
-	for i := uint(0); i < raw58sz-skip; i++ {
-		out[i] = alphabet[rawBase58[i+skip]]
-	}
+	copy(out[:], rawBase58[skip:])
 
 	return raw58sz - skip
 }
 
+// Decode32 decodes a base58 encoded 32-byte value.
+//
+// Inputs must be between 32 and 44 characters long.  Not every 44
+// character string fits in 32 bytes: the largest accepted input is
+// "JEKNVnkbo3jma5nREBBJCDoXFVeKkD56V3xKrvRmWxFG" (2^256-1).
 func Decode32(out *[32]byte, encoded []byte) (ok bool) {
+	var s decodeScratch32
+	return decode32(out, encoded, &s)
+}
+
+// decodeScratch32 holds the intermediate state of a 32-byte decode.
+type decodeScratch32 struct {
+	rawBase58    [45]byte
+	intermediate [9]uint64
+	binary       [8]uint64
+}
+
+func decode32(out *[32]byte, encoded []byte, s *decodeScratch32) (ok bool) {
+	return convert32(out, encoded, s) && isCanonical(out[:], encoded)
+}
+
+// convert32 converts encoded to a 32-byte value without checking that
+// the encoding is canonical.
+func convert32(out *[32]byte, encoded []byte, s *decodeScratch32) (ok bool) {
 	// Check length
-	if len(encoded) < 32 || len(encoded) > 44 {
+	if checkInputLen(len(encoded), 32, 44) != nil {
 		return false
 	}
 
 	// Validate string
-	for _, c := range encoded {
-		idx := int(c) - int(inverseLUTOffset)
-		if idx > int(inverseLUTSentinel) {
-			idx = int(inverseLUTSentinel)
-		}
-		if inverseLUT[idx] == invalidChar {
-			return false
-		}
+	if !validChars(encoded) {
+		return false
 	}
 
 	// X = sum_i raw_base58[i] * 58^(RAW58_SZ-1-i)
-	var rawBase58 [45]byte
+	rawBase58 := &s.rawBase58
 
 	// Prepend enough 0s to make it exactly RAW58_SZ characters
 	prepend0 := 45 - len(encoded)
 	for j := 0; j < 45; j++ {
 		if j >= int(prepend0) {
 			rawBase58[j] = inverseLUT[encoded[j-int(prepend0)]-inverseLUTOffset]
+		} else {
+			rawBase58[j] = 0
 		}
 	}
 
 	// Convert to the intermediate format
 	//   X = sum_i intermediate[i] * 58^(5*(INTERMEDIATE_SZ-1-i))
-	var intermediate [9]uint64
+	intermediate := &s.intermediate
 	for i := 0; i < 9; i++ {
 		intermediate[i] = uint64(rawBase58[5*i+0])*11316496 +
 			uint64(rawBase58[5*i+1])*195112 +
@@ -225,7 +330,7 @@ func Decode32(out *[32]byte, encoded []byte) (ok bool) {
 	//
 	// For N==32, the largest anything in binary can get is binary[7]:
 	// even if intermediate[i]==58^5-1 for all i, then binary[7] < 2^63.
-	var binary_ [8]uint64
+	binary_ := &s.binary
 	for j := 0; j < 8; j++ {
 		var acc uint64
 		for i := 0; i < 9; i++ {
@@ -255,32 +360,448 @@ func Decode32(out *[32]byte, encoded []byte) (ok bool) {
 		binary.BigEndian.PutUint32(out[4*i:], uint32(binary_[i]))
 	}
 
-	// Make sure the encoded version has the same number of leading '1's
-	// as the decoded version has leading 0s. The check doesn't read past
-	// the end of encoded, because '\0' != '1', so it will return NULL.
+	return true
+}
+
+// isCanonical reports whether encoded has exactly as many leading '1's as
+// its decoded value out has leading zero bytes, as the minimal encoding
+// produced by the encoders does.
+func isCanonical(out []byte, encoded []byte) bool {
+	// The check doesn't read past the end of encoded, because the
+	// encoding of a value is never shorter than its leading zeros.
 	var leadingZeroCnt int
-	for leadingZeroCnt = 0; leadingZeroCnt < 32; leadingZeroCnt++ {
+	for leadingZeroCnt = 0; leadingZeroCnt < len(out); leadingZeroCnt++ {
 		if out[leadingZeroCnt] != 0 {
 			break
 		}
-		if encoded[leadingZeroCnt] != '1' {
+		if leadingZeroCnt >= len(encoded) || encoded[leadingZeroCnt] != '1' {
 			return false
 		}
 	}
 	if leadingZeroCnt < len(encoded) && encoded[leadingZeroCnt] == '1' {
 		return false
 	}
+	return true
+}
+
+func Encode64(out *[88]byte, in [64]byte) uint {
+	const raw58sz = 90
+
+	// Count leading zeros (needed for final output)
+	var inLeading0s uint
+	for i := range in {
+		if in[i] != 0 {
+			break
+		}
+		inLeading0s++
+	}
+
+	// Convert N to 32-bit limbs:
+	// X = sum_i binary[i] * 2^(32*(16-1-i))
+	var limbs [16]uint32
+	for i := range limbs {
+		limbs[i] = binary.BigEndian.Uint32(in[4*i:])
+	}
+
+	r1Div := uint64(656356768) // = 58^5
+
+	// Convert to the intermediate format:
+	//   X = sum_i intermediate[i] * 58^(5*(INTERMEDIATE_SZ-1-i))
+	var intermediate [18]uint64
+
+	// If we do it the same way as the 32 byte conversion,
+	// intermediate[16] can overflow when the input is sufficiently
+	// large.  We'll do a mini-reduction after the first 8 steps.
+	// After the first 8 terms, the largest intermediate[16] can be is
+	// 2^63.87.  Then, after reduction it'll be at most 58^5, and after
+	// adding the last terms, it won't exceed 2^63.1.  We do need to be
+	// cautious that the mini-reduction doesn't cause overflow in
+	// intermediate[15] though.  Pre-mini-reduction, it's at most
+	// 2^63.05.  The mini-reduction adds at most 2^64/58^5, which is
+	// negligible.  With the final terms, it won't exceed 2^63.69,
+	// which is fine.  Other terms are less than 2^63.76, so no
+	// problems there.
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 17; j++ {
+			intermediate[j+1] += uint64(limbs[i]) * uint64(encTable64[i][j])
+		}
+	}
+	intermediate[15] += intermediate[16] / r1Div
+	intermediate[16] %= r1Div
+	for i := 8; i < 16; i++ {
+		for j := 0; j < 17; j++ {
+			intermediate[j+1] += uint64(limbs[i]) * uint64(encTable64[i][j])
+		}
+	}
+
+	// Now we make sure each term is less than 58^5.
+	for i := 17; i > 0; i-- {
+		intermediate[i-1] += intermediate[i] / r1Div
+		intermediate[i] %= r1Div
+	}
+
+	// Convert intermediate form to base 58.
+	//   X = sum_i raw_base58[i] * 58^(RAW58_SZ-1-i)
+	var rawBase58 [90]byte
+	for i := 0; i < 18; i++ {
+		v := uint32(intermediate[i])
+		rawBase58[5*i+4] = byte((v / 1) % 58)
+		rawBase58[5*i+3] = byte((v / 58) % 58)
+		rawBase58[5*i+2] = byte((v / 3364) % 58)
+		rawBase58[5*i+1] = byte((v / 195112) % 58)
+		rawBase58[5*i+0] = byte(v / 11316496)
+	}
+
+	// See Encode32 for why rawLeading0s >= inLeading0s.
+	var rawLeading0s uint
+	for rawLeading0s = 0; rawLeading0s < raw58sz; rawLeading0s++ {
+		if rawBase58[rawLeading0s] != 0 {
+			break
+		}
+	}
+
+	skip := rawLeading0s - inLeading0s
+	for i := uint(0); i < raw58sz-skip; i++ {
+		out[i] = alphabet[rawBase58[i+skip]]
+	}
+
+	return raw58sz - skip
+}
+
+// Decode64 decodes a base58 encoded 64-byte value.
+//
+// Inputs must be between 64 and 88 characters long.  The largest
+// accepted input encodes 2^512-1.
+func Decode64(out *[64]byte, encoded []byte) (ok bool) {
+	var s decodeScratch64
+	return decode64(out, encoded, &s)
+}
+
+// decodeScratch64 holds the intermediate state of a 64-byte decode.
+type decodeScratch64 struct {
+	rawBase58    [90]byte
+	intermediate [18]uint64
+	binary       [16]uint64
+}
+
+func decode64(out *[64]byte, encoded []byte, s *decodeScratch64) (ok bool) {
+	return convert64(out, encoded, s) && isCanonical(out[:], encoded)
+}
+
+// convert64 converts encoded to a 64-byte value without checking that
+// the encoding is canonical.
+func convert64(out *[64]byte, encoded []byte, s *decodeScratch64) (ok bool) {
+	// Check length
+	if checkInputLen(len(encoded), 64, 88) != nil {
+		return false
+	}
+
+	// Validate string
+	if !validChars(encoded) {
+		return false
+	}
+
+	// X = sum_i raw_base58[i] * 58^(RAW58_SZ-1-i)
+	rawBase58 := &s.rawBase58
+
+	// Prepend enough 0s to make it exactly RAW58_SZ characters
+	prepend0 := 90 - len(encoded)
+	for j := 0; j < prepend0; j++ {
+		rawBase58[j] = 0
+	}
+	for j := prepend0; j < 90; j++ {
+		rawBase58[j] = inverseLUT[encoded[j-prepend0]-inverseLUTOffset]
+	}
+
+	// Convert to the intermediate format
+	//   X = sum_i intermediate[i] * 58^(5*(INTERMEDIATE_SZ-1-i))
+	intermediate := &s.intermediate
+	for i := 0; i < 18; i++ {
+		intermediate[i] = uint64(rawBase58[5*i+0])*11316496 +
+			uint64(rawBase58[5*i+1])*195112 +
+			uint64(rawBase58[5*i+2])*3364 +
+			uint64(rawBase58[5*i+3])*58 +
+			uint64(rawBase58[5*i+4])
+	}
+
+	// Using the table, convert to overcomplete base 2^32.
+	//
+	// For N==64, the largest anything in binary can get is binary[13]:
+	// even if intermediate[i]==58^5-1 for all i, then binary[13] <
+	// 2^63.998.  Hanging in there, just by a thread!
+	binary_ := &s.binary
+	for j := 0; j < 16; j++ {
+		var acc uint64
+		for i := 0; i < 18; i++ {
+			acc += uint64(intermediate[i]) * uint64(decTable64[i][j])
+		}
+		binary_[j] = acc
+	}
+
+	// Make sure each term is less than 2^32.
+	for i := 15; i > 0; i-- {
+		binary_[i-1] += binary_[i] >> 32
+		binary_[i] &= 0xFFFFFFFF
+	}
+
+	// See Decode32.
+	if binary_[0] > 0xFFFFFFFF {
+		return false
+	}
+
+	for i := 0; i < 16; i++ {
+		binary.BigEndian.PutUint32(out[4*i:], uint32(binary_[i]))
+	}
 
 	return true
 }
 
+// Decode32Padded decodes a base58 encoded 32-byte value read from a
+// fixed-width field, such as a 44-byte column, that pads shorter values
+// with trailing NUL bytes.
+//
+// Only trailing NULs are trimmed.  NULs before or between characters
+// are invalid as usual.
+func Decode32Padded(out *[32]byte, field []byte) bool {
+	return Decode32(out, bytes.TrimRight(field, "\x00"))
+}
+
+// Decode32Func decodes a base58 encoded 32-byte value into an array
+// local to the call and passes it to fn, returning ErrWrongLength or
+// ErrEncode if decoding fails, or else the error returned by fn.
+//
+// Every call decodes into a fresh array, so fn may retain the pointer.
+// Unless both Decode32Func and fn are inlined into the caller, escape
+// analysis cannot see what fn does with it and moves the array to the
+// heap.
+func Decode32Func(encoded []byte, fn func(out *[32]byte) error) error {
+	var out [32]byte
+	if err := decode32Err(&out, encoded); err != nil {
+		return decodeFailed(err)
+	}
+	return fn(&out)
+}
+
+// Decode32Full decodes a base58 encoded 32-byte value and reports
+// whether the input was its canonical encoding.
+//
+// Inputs with more or fewer leading '1' characters than the value has
+// leading zero bytes are decoded with canonical set to false.  Inputs
+// of the wrong length return ErrWrongLength, and inputs containing
+// invalid characters or exceeding 2^256-1 return ErrEncode.
+func Decode32Full(encoded []byte) (out [32]byte, canonical bool, err error) {
+	if err := inputLenError32(len(encoded)); err != nil {
+		return out, false, decodeFailed(err)
+	}
+	var s decodeScratch32
+	if !convert32(&out, encoded, &s) {
+		return out, false, decodeFailed(ErrEncode)
+	}
+	return out, isCanonical(out[:], encoded), nil
+}
+
+// IsValid32 reports whether encoded is the canonical encoding of a
+// 32-byte value, as accepted by Decode32.
+//
+// Unlike Decode32, it never converts the value.  It returns false at
+// the first character outside the alphabet, and otherwise checks the
+// range and leading zeros by comparing digit strings against the
+// encodings of powers of 256, which base58 orders like the values.
+func IsValid32(encoded []byte) bool {
+	if checkInputLen(len(encoded), 32, 44) != nil || !validChars(encoded) {
+		return false
+	}
+	ones := 0
+	for ones < len(encoded) && encoded[ones] == '1' {
+		ones++
+	}
+	digits := encoded[ones:]
+	if len(digits) == 0 {
+		// Zero is encoded as one '1' per byte.
+		return ones == 32
+	}
+	// A non-zero value has exactly as many leading zero bytes as the
+	// encoding has leading '1's: 256^(n-1) <= value < 256^n for the
+	// remaining byte length n.  The upper bound for n = 32 is 2^256.
+	n := 32 - ones
+	if n < 1 || compareDigits(digits, pow256Digits[n-1]) < 0 {
+		return false
+	}
+	if n < 32 {
+		return compareDigits(digits, pow256Digits[n]) < 0
+	}
+	return len(digits) < 44 || string(digits) <= max32Digits
+}
+
+// pow256Digits holds the base58 digits of 256^k for k < 32, and
+// max32Digits those of 2^256-1.
+var (
+	pow256Digits [32]string
+	max32Digits  string
+)
+
+func init() {
+	var out [44]byte
+	for k := range pow256Digits {
+		var in [32]byte
+		in[31-k] = 1
+		n := Encode32(&out, in)
+		pow256Digits[k] = strings.TrimLeft(string(out[:n]), "1")
+	}
+	var all [32]byte
+	for i := range all {
+		all[i] = 0xFF
+	}
+	max32Digits = string(out[:Encode32(&out, all)])
+}
+
+// compareDigits compares two base58 digit strings without leading '1's
+// by value.  The alphabet is in ASCII order, so strings of equal length
+// compare like their values.
+func compareDigits(a []byte, b string) int {
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	switch {
+	case string(a) < b:
+		return -1
+	case string(a) > b:
+		return 1
+	}
+	return 0
+}
+
+// validChars returns whether all characters of encoded are in the alphabet.
+// It stops at the first invalid character, so that decoders reject
+// garbage without doing any conversion work.
+func validChars(encoded []byte) bool {
+	for _, c := range encoded {
+		idx := int(c) - int(inverseLUTOffset)
+		if idx < 0 {
+			return false
+		}
+		if idx > int(inverseLUTSentinel) {
+			idx = int(inverseLUTSentinel)
+		}
+		if inverseLUT[idx] == invalidChar {
+			return false
+		}
+	}
+	return true
+}
+
+// EncodedLen32 returns the number of characters Encode32 produces for in.
+func EncodedLen32(in [32]byte) int {
+	return encodedLen(pow58x32, in[:])
+}
+
+// LengthClass32 returns the number of characters in the base58 encoding
+// of in, between 32 and 44, without encoding it.  It is a cheap key to
+// bucket or shard values by: keys with more leading zero bytes or a
+// smaller magnitude fall into lower classes.
+func LengthClass32(in [32]byte) int {
+	return EncodedLen32(in)
+}
+
+// EncodedLen64 returns the number of characters Encode64 produces for in.
+func EncodedLen64(in [64]byte) int {
+	return encodedLen(pow58x64, in[:])
+}
+
+// pow58x32 and pow58x64 hold 58^k as big-endian integers, for all
+// 58^k that fit in 32 and 64 bytes respectively.
+var (
+	pow58x32 = powersOf58(32)
+	pow58x64 = powersOf58(64)
+)
+
+func powersOf58(n int) [][]byte {
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(8*n))
+	var pows [][]byte
+	for p := big.NewInt(1); p.Cmp(limit) < 0; p.Mul(p, big.NewInt(58)) {
+		pows = append(pows, p.FillBytes(make([]byte, n)))
+	}
+	return pows
+}
+
+func encodedLen(pows [][]byte, in []byte) int {
+	// Every leading zero byte becomes a '1'.
+	var leading0s int
+	for leading0s < len(in) && in[leading0s] == 0 {
+		leading0s++
+	}
+	// The remaining value X has floor(log_58 X)+1 digits, which is the
+	// number of powers of 58 less than or equal to X.
+	digits := sort.Search(len(pows), func(k int) bool {
+		return bytes.Compare(pows[k], in) > 0
+	})
+	return leading0s + digits
+}
+
+// Encode returns the base58 encoding of a 32 or 64 byte value.
+// An empty buf encodes to the empty string.
+// Encode panics for any other length.
 func Encode(buf []byte) string {
 	switch len(buf) {
+	case 0:
+		return ""
 	case 32:
 		var out [44]byte
 		outLen := Encode32(&out, *(*[32]byte)(buf))
 		return string(out[:outLen])
+	case 64:
+		var out [88]byte
+		outLen := Encode64(&out, *(*[64]byte)(buf))
+		return string(out[:outLen])
 	default:
 		panic("unsupported base58 length")
 	}
 }
+
+// Encode32Fixed returns the base58 encoding of in left-padded with '1'
+// characters to 44, the longest encoding of a 32-byte value, for
+// aligned columnar display.
+//
+// The padding is for display only: it adds leading '1's the value's
+// leading zero bytes do not account for, so the result is not the
+// canonical encoding and Decode32 rejects it.  Decode32Full decodes it
+// back to in, reporting it as non-canonical.
+func Encode32Fixed(in [32]byte) string {
+	var out [44]byte
+	n := Encode32(&out, in)
+	copy(out[44-n:], out[:n])
+	for i := uint(0); i < 44-n; i++ {
+		out[i] = '1'
+	}
+	return string(out[:])
+}
+
+// EncodeAll32 returns the base58 encodings of keys, in order.
+// A single scratch buffer is reused for every key, so the only
+// allocations are the result slice and its strings.
+func EncodeAll32(keys [][32]byte) []string {
+	out := make([]string, len(keys))
+	var buf [44]byte
+	for i, key := range keys {
+		n := Encode32(&buf, key)
+		out[i] = string(buf[:n])
+	}
+	return out
+}
+
+// MatchesTruncated reports whether the base58 encoding of full starts
+// with prefix and ends with suffix, as when matching a shortened
+// display such as "4JqF...r8fC" against known keys.  Either may be
+// empty.  Prefixes or suffixes containing characters outside the
+// alphabet never match.
+func MatchesTruncated(full [32]byte, prefix, suffix string) bool {
+	if !validChars([]byte(prefix)) || !validChars([]byte(suffix)) {
+		return false
+	}
+	var out [44]byte
+	enc := out[:Encode32(&out, full)]
+	return bytes.HasPrefix(enc, []byte(prefix)) && bytes.HasSuffix(enc, []byte(suffix))
+}
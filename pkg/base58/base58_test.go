@@ -1,8 +1,16 @@
 package base58
 
 import (
+	"bytes"
 	"encoding/hex"
+	"errors"
+	"math/big"
+	"math/rand"
+	"strings"
 	"testing"
+	"testing/quick"
+
+	"go.firedancer.io/radiance/pkg/base58/internal/gentables"
 )
 
 var testVector32 = []struct {
@@ -29,6 +37,10 @@ var testVector32 = []struct {
 		hex: "fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe",
 		b58: "JEKNVnkbo3jma5nREBBJCDoXFVeKkD56V3xKrvRmWxFF",
 	},
+	{
+		hex: "31247ddec47363a76c2a61eb32e70233a5df1addf0c46ab6d86965941325711b",
+		b58: "4JqFY5UL9nx2H16KoEGCe7XfyzYiXawQyRhZA18Yr8fC",
+	},
 }
 
 func TestEncode32(t *testing.T) {
@@ -60,3 +72,922 @@ func TestDecode32(t *testing.T) {
 		}
 	}
 }
+
+var testVector64 = []struct {
+	hex string
+	b58 string
+}{
+	{
+		hex: "00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		b58: "1111111111111111111111111111111111111111111111111111111111111111",
+	},
+	{
+		hex: "00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000001",
+		b58: "1111111111111111111111111111111111111111111111111111111111111112",
+	},
+	{
+		hex: "00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000101",
+		b58: "111111111111111111111111111111111111111111111111111111111111115S",
+	},
+	{
+		hex: "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		b58: "67rpwLCuS5DGA8KGZXKsVQ7dnPb9goRLoKfgGbLfQg9WoLUgNY77E2jT11fem3coV9nAkguBACzrU1iyZM4B8roQ",
+	},
+	{
+		hex: "fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe",
+		b58: "67rpwLCuS5DGA8KGZXKsVQ7dnPb9goRLoKfgGbLfQg9WoLUgNY77E2jT11fem3coV9nAkguBACzrU1iyZM4B8roP",
+	},
+	{
+		hex: "31247ddec47363a76c2a61eb32e70233a5df1addf0c46ab6d86965941325711bb67db9421dcf30029b392084c5798053676ad06755f7e4e251d0de57ae7bc96c",
+		b58: "yzBqSUY36udnBKboxXQvP17hxqUbdDZaBUweNAkyjH4GRWsNuqrh2RzaDnc4JV5a2haMF5HpDbSVLmjtpX8vFb9",
+	},
+}
+
+func TestEncode64(t *testing.T) {
+	for _, test := range testVector64 {
+		var in [64]byte
+		hex.Decode(in[:], []byte(test.hex))
+
+		var out [88]byte
+		outLen := Encode64(&out, in)
+
+		outStr := string(out[:outLen])
+		if outStr != test.b58 {
+			t.Errorf("Encode64(%s) = %s, want %s", test.hex, outStr, test.b58)
+		}
+	}
+}
+
+func TestDecode64(t *testing.T) {
+	for _, test := range testVector64 {
+		var out [64]byte
+		if !Decode64(&out, []byte(test.b58)) {
+			t.Errorf("Decode64(%s) failed", test.b58)
+			continue
+		}
+
+		outStr := hex.EncodeToString(out[:])
+		if outStr != test.hex {
+			t.Errorf("Decode64(%s) = %s, want %s", test.b58, outStr, test.hex)
+		}
+	}
+}
+
+func TestDecode_InvalidChars(t *testing.T) {
+	var out32 [32]byte
+	var out64 [64]byte
+	for _, c := range []byte{0x00, ' ', '0', 'I', 'O', 'l', '{', 0xFF} {
+		in := bytes.Repeat([]byte{'2'}, 64)
+		in[5] = c
+		if Decode32(&out32, in[:32]) {
+			t.Errorf("Decode32 accepted %q", c)
+		}
+		if Decode64(&out64, in) {
+			t.Errorf("Decode64 accepted %q", c)
+		}
+	}
+}
+
+// TestDecode_SentinelBoundary pins the clamping of characters above 'z'
+// onto the sentinel slot of inverseLUT, which directly follows 'z'.
+func TestDecode_SentinelBoundary(t *testing.T) {
+	if got := inverseLUT['z'-inverseLUTOffset]; got != 57 {
+		t.Errorf("inverseLUT['z'] = %d, want 57", got)
+	}
+	if got := inverseLUT[inverseLUTSentinel]; got != invalidChar {
+		t.Errorf("inverseLUT[sentinel] = %#x, want invalidChar", got)
+	}
+
+	var out [32]byte
+	if !Decode32(&out, []byte("1111111111111111111111111111111z")) || out[31] != 57 {
+		t.Errorf("Decode32 of trailing 'z' = %x", out)
+	}
+	for c := 'z' + 1; c <= 0xFF; c++ {
+		in := []byte("1111111111111111111111111111111z")
+		in[31] = byte(c)
+		if validChars(in) || Decode32(&out, in) {
+			t.Errorf("Decode32 accepted %q", c)
+		}
+	}
+}
+
+func TestQuick_Roundtrip32(t *testing.T) {
+	f := func(in [32]byte) bool {
+		var enc [44]byte
+		n := Encode32(&enc, in)
+		if n < 32 || n > 44 {
+			return false
+		}
+		var dec [32]byte
+		return Decode32(&dec, enc[:n]) && dec == in
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQuick_Roundtrip64(t *testing.T) {
+	f := func(in [64]byte) bool {
+		var enc [88]byte
+		n := Encode64(&enc, in)
+		if n < 64 || n > 88 {
+			return false
+		}
+		var dec [64]byte
+		return Decode64(&dec, enc[:n]) && dec == in
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQuick_Mutate32(t *testing.T) {
+	f := func(in [32]byte, pos uint8, c byte) bool {
+		var enc [44]byte
+		n := Encode32(&enc, in)
+		i := int(pos) % int(n)
+		c = alphabet[int(c)%len(alphabet)]
+		if enc[i] == c {
+			return true
+		}
+		enc[i] = c
+		var dec [32]byte
+		return !Decode32(&dec, enc[:n]) || dec != in
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQuick_Mutate64(t *testing.T) {
+	f := func(in [64]byte, pos uint8, c byte) bool {
+		var enc [88]byte
+		n := Encode64(&enc, in)
+		i := int(pos) % int(n)
+		c = alphabet[int(c)%len(alphabet)]
+		if enc[i] == c {
+			return true
+		}
+		enc[i] = c
+		var dec [64]byte
+		return !Decode64(&dec, enc[:n]) || dec != in
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncodedLen(t *testing.T) {
+	for _, test := range testVector32 {
+		var in [32]byte
+		hex.Decode(in[:], []byte(test.hex))
+		if n := EncodedLen32(in); n != len(test.b58) {
+			t.Errorf("EncodedLen32(%s) = %d, want %d", test.hex, n, len(test.b58))
+		}
+	}
+	for _, test := range testVector64 {
+		var in [64]byte
+		hex.Decode(in[:], []byte(test.hex))
+		if n := EncodedLen64(in); n != len(test.b58) {
+			t.Errorf("EncodedLen64(%s) = %d, want %d", test.hex, n, len(test.b58))
+		}
+	}
+}
+
+func TestQuick_EncodedLen32(t *testing.T) {
+	f := func(in [32]byte, zeros uint8) bool {
+		copy(in[:], make([]byte, int(zeros)%33))
+		var enc [44]byte
+		return EncodedLen32(in) == int(Encode32(&enc, in))
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLengthClass32(t *testing.T) {
+	tests := []struct {
+		hex   string
+		class int
+	}{
+		{"0000000000000000000000000000000000000000000000000000000000000000", 32},
+		{"0000000000000000000000000000000000000000000000000000000000000101", 32},
+		{"00ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", 44},
+		{"31247ddec47363a76c2a61eb32e70233a5df1addf0c46ab6d86965941325711b", 44},
+		{"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", 44},
+	}
+	for _, test := range tests {
+		var in [32]byte
+		hex.Decode(in[:], []byte(test.hex))
+		if c := LengthClass32(in); c != test.class {
+			t.Errorf("LengthClass32(%s) = %d, want %d", test.hex, c, test.class)
+		}
+		if c, n := LengthClass32(in), len(Encode(in[:])); c != n {
+			t.Errorf("LengthClass32(%s) = %d, but encoding has %d chars", test.hex, c, n)
+		}
+	}
+
+	f := func(in [32]byte, zeros uint8) bool {
+		copy(in[:], make([]byte, int(zeros)%33))
+		return LengthClass32(in) == len(Encode(in[:]))
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestCodec documents the intended usage of Codec: allocate once,
+// then decode in a loop without touching the stack for scratch space.
+func TestCodec(t *testing.T) {
+	c := NewCodec()
+	for i := 0; i < 2; i++ {
+		for _, test := range testVector32 {
+			var out [32]byte
+			if !c.Decode32(&out, []byte(test.b58)) || hex.EncodeToString(out[:]) != test.hex {
+				t.Errorf("Codec.Decode32(%s) failed", test.b58)
+			}
+		}
+		for _, test := range testVector64 {
+			var out [64]byte
+			if !c.Decode64(&out, []byte(test.b58)) || hex.EncodeToString(out[:]) != test.hex {
+				t.Errorf("Codec.Decode64(%s) failed", test.b58)
+			}
+		}
+	}
+}
+
+func BenchmarkDecode64(b *testing.B) {
+	in := []byte(testVector64[3].b58)
+	var out [64]byte
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Decode64(&out, in)
+	}
+}
+
+// benchInputs32 and benchInputs64 are the minimal, a typical, and the
+// maximal encodings of each size.
+var (
+	benchInputs32 = []struct{ name, b58 string }{
+		{"Min", testVector32[0].b58},
+		{"Typical", testVector32[5].b58},
+		{"Max", testVector32[3].b58},
+	}
+	benchInputs64 = []struct{ name, b58 string }{
+		{"Min", testVector64[0].b58},
+		{"Typical", testVector64[5].b58},
+		{"Max", testVector64[3].b58},
+	}
+)
+
+func BenchmarkDecode32_Inputs(b *testing.B) {
+	for _, bc := range benchInputs32 {
+		b.Run(bc.name, func(b *testing.B) {
+			in := []byte(bc.b58)
+			var out [32]byte
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				Decode32(&out, in)
+			}
+		})
+	}
+}
+
+func BenchmarkDecode64_Inputs(b *testing.B) {
+	for _, bc := range benchInputs64 {
+		b.Run(bc.name, func(b *testing.B) {
+			in := []byte(bc.b58)
+			var out [64]byte
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				Decode64(&out, in)
+			}
+		})
+	}
+}
+
+func BenchmarkCodec_Decode64(b *testing.B) {
+	in := []byte(testVector64[3].b58)
+	c := NewCodec()
+	var out [64]byte
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.Decode64(&out, in)
+	}
+}
+
+func TestCodec_DecodeAppend(t *testing.T) {
+	c := NewCodec()
+	var dst []byte
+	var want []byte
+	for _, test := range testVector32 {
+		var err error
+		dst, err = c.DecodeAppend(dst, []byte(test.b58))
+		if err != nil {
+			t.Fatalf("DecodeAppend(%s) failed: %v", test.b58, err)
+		}
+		raw, _ := hex.DecodeString(test.hex)
+		want = append(want, raw...)
+	}
+	for _, test := range testVector64 {
+		var err error
+		dst, err = c.DecodeAppend(dst, []byte(test.b58))
+		if err != nil {
+			t.Fatalf("DecodeAppend(%s) failed: %v", test.b58, err)
+		}
+		raw, _ := hex.DecodeString(test.hex)
+		want = append(want, raw...)
+	}
+	if !bytes.Equal(dst, want) {
+		t.Errorf("DecodeAppend = %x, want %x", dst, want)
+	}
+
+	for _, bad := range []string{"", "1111", "0EKNVnkbo3jma5nREBBJCDoXFVeKkD56V3xKrvRmWxFG"} {
+		got, err := c.DecodeAppend(dst, []byte(bad))
+		if err == nil {
+			t.Errorf("DecodeAppend(%s) succeeded", bad)
+		}
+		if len(got) != len(dst) {
+			t.Errorf("DecodeAppend(%s) changed length on failure", bad)
+		}
+	}
+
+	// A failed decode must not scribble over spare capacity.
+	spare := bytes.Repeat([]byte{0xAA}, 64)[:0]
+	bad := "1" + testVector32[2].b58 // non-canonical, fails after converting
+	if got, err := c.DecodeAppend(spare, []byte(bad)); err == nil || len(got) != 0 {
+		t.Errorf("DecodeAppend(%s) = %x, %v", bad, got, err)
+	}
+	if !bytes.Equal(spare[:64], bytes.Repeat([]byte{0xAA}, 64)) {
+		t.Errorf("DecodeAppend wrote %x into spare capacity on failure", spare[:64])
+	}
+}
+
+func BenchmarkCodec_DecodeAppend(b *testing.B) {
+	in := []byte(testVector64[3].b58)
+	c := NewCodec()
+	dst := make([]byte, 0, 64)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var err error
+		dst, err = c.DecodeAppend(dst[:0], in)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDecode_OverflowBoundary(t *testing.T) {
+	var out32 [32]byte
+	// 2^256-1 and 2^256
+	if !Decode32(&out32, []byte("JEKNVnkbo3jma5nREBBJCDoXFVeKkD56V3xKrvRmWxFG")) {
+		t.Error("Decode32 rejected 2^256-1")
+	}
+	if Decode32(&out32, []byte("JEKNVnkbo3jma5nREBBJCDoXFVeKkD56V3xKrvRmWxFH")) {
+		t.Error("Decode32 accepted 2^256")
+	}
+	if Decode32(&out32, []byte("zzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzz")) {
+		t.Error("Decode32 accepted 58^44-1")
+	}
+
+	var out64 [64]byte
+	// 2^512-1 and 2^512
+	if !Decode64(&out64, []byte("67rpwLCuS5DGA8KGZXKsVQ7dnPb9goRLoKfgGbLfQg9WoLUgNY77E2jT11fem3coV9nAkguBACzrU1iyZM4B8roQ")) {
+		t.Error("Decode64 rejected 2^512-1")
+	}
+	if Decode64(&out64, []byte("67rpwLCuS5DGA8KGZXKsVQ7dnPb9goRLoKfgGbLfQg9WoLUgNY77E2jT11fem3coV9nAkguBACzrU1iyZM4B8roR")) {
+		t.Error("Decode64 accepted 2^512")
+	}
+}
+
+func TestEncode_Empty(t *testing.T) {
+	if s := Encode(nil); s != "" {
+		t.Errorf("Encode(nil) = %q", s)
+	}
+	if s := Encode([]byte{}); s != "" {
+		t.Errorf("Encode([]byte{}) = %q", s)
+	}
+}
+
+func TestMaxDecodeInputLen(t *testing.T) {
+	huge := bytes.Repeat([]byte{'2'}, 1<<20)
+
+	var out32 [32]byte
+	if Decode32(&out32, huge) {
+		t.Error("Decode32 accepted 1MB input")
+	}
+	var out64 [64]byte
+	if Decode64(&out64, huge) {
+		t.Error("Decode64 accepted 1MB input")
+	}
+	if _, err := NewCodec().DecodeAppend(nil, huge); err != ErrWrongLength {
+		t.Errorf("DecodeAppend = %v, want ErrWrongLength", err)
+	}
+	if _, err := ParsePubkey(string(huge)); !errors.Is(err, ErrWrongLength) {
+		t.Errorf("ParsePubkey = %v, want ErrWrongLength", err)
+	}
+	if err := Decode32Checked(&out32, huge, false); !errors.Is(err, ErrWrongLength) {
+		t.Errorf("Decode32Checked = %v, want ErrWrongLength", err)
+	}
+
+	// Lowering the limit below a value's encoded length rejects it.
+	defer func(max int) { MaxDecodeInputLen = max }(MaxDecodeInputLen)
+	MaxDecodeInputLen = 40
+	if Decode32(&out32, []byte(testVector32[3].b58)) {
+		t.Error("Decode32 accepted input longer than MaxDecodeInputLen")
+	}
+}
+
+func TestTablesMatchGenerator(t *testing.T) {
+	checkTable := func(name string, want [][]uint32, got [][]uint32) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("%s: got %d rows, want %d", name, len(got), len(want))
+		}
+		for j := range want {
+			for k := range want[j] {
+				if got[j][k] != want[j][k] {
+					t.Errorf("%s[%d][%d] = %d, want %d (run go generate)", name, j, k, got[j][k], want[j][k])
+				}
+			}
+		}
+	}
+	rows := func(n int, row func(j int) []uint32) [][]uint32 {
+		out := make([][]uint32, n)
+		for j := range out {
+			out[j] = row(j)
+		}
+		return out
+	}
+	checkTable("encTable32", gentables.EncTable(32), rows(len(encTable32), func(j int) []uint32 { return encTable32[j][:] }))
+	checkTable("decTable32", gentables.DecTable(32), rows(len(decTable32), func(j int) []uint32 { return decTable32[j][:] }))
+	checkTable("encTable64", gentables.EncTable(64), rows(len(encTable64), func(j int) []uint32 { return encTable64[j][:] }))
+	checkTable("decTable64", gentables.DecTable(64), rows(len(decTable64), func(j int) []uint32 { return decTable64[j][:] }))
+}
+
+func TestDigits32(t *testing.T) {
+	f := func(in [32]byte) bool {
+		digits := Digits32(in)
+		str := make([]byte, len(digits))
+		for i, d := range digits {
+			if d >= 58 {
+				return false
+			}
+			str[i] = alphabet[d]
+		}
+		return string(str) == Encode(in[:])
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+	if got := Digits32([32]byte{}); !bytes.Equal(got, make([]byte, 32)) {
+		t.Errorf("Digits32(0) = %v", got)
+	}
+}
+
+func TestDecodeGrouped(t *testing.T) {
+	want, _ := hex.DecodeString(testVector32[5].hex)
+	for _, test := range []struct {
+		s   string
+		sep rune
+	}{
+		{"4JqF Y5UL 9nx2 H16K oEGC e7Xf yzYi XawQ yRhZ A18Y r8fC", ' '},
+		{"4JqF-Y5UL-9nx2-H16K-oEGC-e7Xf-yzYi-XawQ-yRhZ-A18Y-r8fC", '-'},
+		{testVector32[5].b58, ' '},
+	} {
+		got, err := DecodeGrouped(test.s, test.sep)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("DecodeGrouped(%q) = %x, %v", test.s, got, err)
+		}
+	}
+
+	got, err := DecodeGrouped(testVector64[5].b58[:40]+" "+testVector64[5].b58[40:], ' ')
+	if want, _ := hex.DecodeString(testVector64[5].hex); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("DecodeGrouped(64) = %x, %v", got, err)
+	}
+
+	if _, err := DecodeGrouped("4JqFxY5UL", 'x'); err == nil {
+		t.Error("DecodeGrouped accepted a base58 digit as separator")
+	}
+
+	long := testVector32[5].b58 + strings.Repeat(" ", MaxDecodeInputLen)
+	if _, err := DecodeGrouped(long, ' '); !errors.Is(err, ErrWrongLength) {
+		t.Errorf("DecodeGrouped(%d bytes) error = %v, want ErrWrongLength", len(long), err)
+	}
+}
+
+func TestDecode32Full(t *testing.T) {
+	for _, test := range testVector32 {
+		out, canonical, err := Decode32Full([]byte(test.b58))
+		if err != nil || !canonical || hex.EncodeToString(out[:]) != test.hex {
+			t.Errorf("Decode32Full(%s) = %x, %v, %v", test.b58, out, canonical, err)
+		}
+	}
+
+	// Non-canonical: one extra or one missing leading '1'.
+	for _, test := range []struct {
+		b58 string
+		hex string
+	}{
+		{"1" + testVector32[0].b58, testVector32[0].hex},
+		{"1" + testVector32[2].b58, testVector32[2].hex},
+		{"4uQeVj5tqViQh7yWWGStvkEG1Zmhx6uasJtWCJziofL", "00ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+	} {
+		out, canonical, err := Decode32Full([]byte(test.b58))
+		if err != nil || canonical || hex.EncodeToString(out[:]) != test.hex {
+			t.Errorf("Decode32Full(%s) = %x, %v, %v", test.b58, out, canonical, err)
+		}
+		var dec [32]byte
+		if Decode32(&dec, []byte(test.b58)) {
+			t.Errorf("Decode32(%s) accepted non-canonical input", test.b58)
+		}
+	}
+
+	for _, test := range []struct {
+		b58 string
+		err error
+	}{
+		{"1111", ErrWrongLength},
+		{"0" + testVector32[5].b58[1:], ErrEncode},
+		{"zzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzz", ErrEncode},
+	} {
+		if _, _, err := Decode32Full([]byte(test.b58)); !errors.Is(err, test.err) {
+			t.Errorf("Decode32Full(%s) error = %v, want %v", test.b58, err, test.err)
+		}
+	}
+}
+
+func TestIsValid32(t *testing.T) {
+	for _, test := range testVector32 {
+		if !IsValid32([]byte(test.b58)) {
+			t.Errorf("IsValid32(%s) = false", test.b58)
+		}
+	}
+	for _, s := range []string{
+		"",
+		"0" + testVector32[5].b58[1:],
+		testVector32[5].b58[:2] + "\x00" + testVector32[5].b58[3:],
+		"1" + testVector32[0].b58,
+		"zzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzz",
+	} {
+		if IsValid32([]byte(s)) {
+			t.Errorf("IsValid32(%q) = true", s)
+		}
+	}
+}
+
+// TestIsValid32_MatchesDecode32 checks that the conversion-free
+// validation agrees with Decode32, particularly around the boundaries
+// between byte lengths, where the number of leading '1's matters.
+func TestIsValid32_MatchesDecode32(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	check := func(in []byte) {
+		var out [32]byte
+		if got, want := IsValid32(in), Decode32(&out, in); got != want {
+			t.Errorf("IsValid32(%s) = %t, Decode32 = %t", in, got, want)
+		}
+	}
+	var enc [44]byte
+	for i := 0; i < 20000; i++ {
+		// Values around 256^k and 2^256, with their neighbours.
+		var v [32]byte
+		k := rng.Intn(33)
+		switch rng.Intn(3) {
+		case 0:
+			if k < 32 {
+				v[31-k] = 1
+			}
+		case 1:
+			for j := 32 - k; j < 32; j++ {
+				v[j] = 0xFF
+			}
+		default:
+			rng.Read(v[32-k:])
+		}
+		s := enc[:Encode32(&enc, v)]
+		check(s)
+		check(append([]byte("1"), s...))
+		if s[0] == '1' {
+			check(s[1:])
+		}
+		// Perturb the last digit.
+		p := append([]byte(nil), s...)
+		p[len(p)-1] = alphabet[rng.Intn(58)]
+		check(p)
+
+		// Random digit strings of every accepted length.
+		r := make([]byte, 32+rng.Intn(13))
+		ones := rng.Intn(len(r))
+		for j := range r {
+			if j < ones {
+				r[j] = '1'
+			} else {
+				r[j] = alphabet[rng.Intn(58)]
+			}
+		}
+		check(r)
+	}
+}
+
+func BenchmarkIsValid32(b *testing.B) {
+	valid := testVector32[5].b58
+	for _, bc := range []struct{ name, in string }{
+		{"InvalidAt2", valid[:2] + "0" + valid[3:]},
+		{"InvalidAtEnd", valid[:len(valid)-1] + "0"},
+		{"Valid", valid},
+	} {
+		in := []byte(bc.in)
+		b.Run(bc.name+"/IsValid32", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				IsValid32(in)
+			}
+		})
+		b.Run(bc.name+"/Decode32", func(b *testing.B) {
+			var out [32]byte
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				Decode32(&out, in)
+			}
+		})
+	}
+}
+
+func TestDecode32Func(t *testing.T) {
+	var got [32]byte
+	err := Decode32Func([]byte(testVector32[5].b58), func(out *[32]byte) error {
+		got = *out
+		return nil
+	})
+	if err != nil || hex.EncodeToString(got[:]) != testVector32[5].hex {
+		t.Errorf("Decode32Func() = %x, %v", got, err)
+	}
+
+	// Each call gets its own array, so a retained result stays intact.
+	var kept []*[32]byte
+	for _, test := range testVector32 {
+		_ = Decode32Func([]byte(test.b58), func(out *[32]byte) error {
+			kept = append(kept, out)
+			return nil
+		})
+	}
+	for i, test := range testVector32 {
+		if hex.EncodeToString(kept[i][:]) != test.hex {
+			t.Errorf("retained result %d = %x, want %s", i, kept[i][:], test.hex)
+		}
+	}
+
+	if err := Decode32Func([]byte("1111"), nil); !errors.Is(err, ErrWrongLength) {
+		t.Errorf("Decode32Func(short) error = %v", err)
+	}
+	errStop := errors.New("stop")
+	if err := Decode32Func([]byte(testVector32[5].b58), func(*[32]byte) error { return errStop }); err != errStop {
+		t.Errorf("Decode32Func() error = %v, want callback error", err)
+	}
+}
+
+// BenchmarkDecode32Func reports one 32-byte allocation per call: the
+// callback is a func value opaque to escape analysis, so the local
+// result array reaching it is moved to the heap ("moved to heap: out"
+// in go build -gcflags=-m).  The decode itself does not allocate.
+func BenchmarkDecode32Func(b *testing.B) {
+	in := []byte(testVector32[5].b58)
+	var sum byte
+	fn := func(out *[32]byte) error {
+		sum += out[0]
+		return nil
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := Decode32Func(in, fn); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if n := testing.AllocsPerRun(10, func() { _ = Decode32Func(in, fn) }); n > 1 {
+		b.Errorf("Decode32Func allocated %v times", n)
+	}
+}
+
+func TestDecode32Padded(t *testing.T) {
+	var field [44]byte
+	copy(field[:], testVector32[0].b58)
+	var out [32]byte
+	if !Decode32Padded(&out, field[:]) || out != [32]byte{} {
+		t.Errorf("Decode32Padded(%q) = %x", field, out)
+	}
+	if !Decode32Padded(&out, []byte(testVector32[5].b58)) || hex.EncodeToString(out[:]) != testVector32[5].hex {
+		t.Errorf("Decode32Padded(unpadded) = %x", out)
+	}
+
+	copy(field[:], testVector32[0].b58)
+	field[5] = 0
+	if Decode32Padded(&out, field[:]) {
+		t.Error("Decode32Padded accepted an interior NUL")
+	}
+	copy(field[1:], testVector32[0].b58)
+	field[0] = 0
+	if Decode32Padded(&out, field[:]) {
+		t.Error("Decode32Padded accepted a leading NUL")
+	}
+}
+
+// encodeBig is a reference base58 encoder using math/big.
+func encodeBig(in []byte) string {
+	x := new(big.Int).SetBytes(in)
+	var digits []byte
+	mod := new(big.Int)
+	for x.Sign() > 0 {
+		x.DivMod(x, big.NewInt(58), mod)
+		digits = append(digits, alphabet[mod.Int64()])
+	}
+	for _, b := range in {
+		if b != 0 {
+			break
+		}
+		digits = append(digits, '1')
+	}
+	for i, j := 0, len(digits)-1; i < j; i, j = i+1, j-1 {
+		digits[i], digits[j] = digits[j], digits[i]
+	}
+	return string(digits)
+}
+
+func TestEncode32_FastPath(t *testing.T) {
+	check := func(in [32]byte) bool {
+		var out [44]byte
+		n := Encode32(&out, in)
+		return string(out[:n]) == encodeBig(in[:])
+	}
+	for _, test := range testVector32 {
+		var in [32]byte
+		hex.Decode(in[:], []byte(test.hex))
+		if !check(in) {
+			t.Errorf("Encode32(%s) differs from reference", test.hex)
+		}
+	}
+	// The smallest values taking the fast path, with 43 and 44 digits.
+	if !check([32]byte{1}) || !check([32]byte{0xff, 0xff}) {
+		t.Error("Encode32 differs from reference at fast path boundary")
+	}
+	if err := quick.Check(check, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func BenchmarkEncode32(b *testing.B) {
+	for _, bc := range []struct{ name, hex string }{
+		{"Typical", testVector32[5].hex},
+		{"LeadingZeros", testVector32[2].hex},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			var in [32]byte
+			hex.Decode(in[:], []byte(bc.hex))
+			var out [44]byte
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				Encode32(&out, in)
+			}
+		})
+	}
+}
+
+func TestEncodeAll32(t *testing.T) {
+	if got := EncodeAll32(nil); len(got) != 0 {
+		t.Errorf("EncodeAll32(nil) = %q, want empty", got)
+	}
+
+	keys := make([][32]byte, len(testVector32))
+	for i, test := range testVector32 {
+		hex.Decode(keys[i][:], []byte(test.hex))
+	}
+	got := EncodeAll32(keys)
+	if len(got) != len(keys) {
+		t.Fatalf("EncodeAll32 returned %d strings, want %d", len(got), len(keys))
+	}
+	for i := range keys {
+		if want := Encode(keys[i][:]); got[i] != want {
+			t.Errorf("EncodeAll32()[%d] = %s, want %s", i, got[i], want)
+		}
+	}
+}
+
+func TestOnDecodeError(t *testing.T) {
+	var reasons []error
+	OnDecodeError = func(reason error) { reasons = append(reasons, reason) }
+	defer func() { OnDecodeError = nil }()
+
+	if _, err := ParsePubkey("11111111111111111111111111111110"); !errors.Is(err, ErrEncode) {
+		t.Fatalf("ParsePubkey: err = %v, want ErrEncode", err)
+	}
+	if _, _, err := Decode32Full([]byte("short")); !errors.Is(err, ErrWrongLength) {
+		t.Fatalf("Decode32Full: err = %v, want ErrWrongLength", err)
+	}
+	if _, err := ParsePubkey(testVector32[5].b58); err != nil {
+		t.Fatal(err)
+	}
+	if len(reasons) != 2 || reasons[0] != ErrEncode || !errors.Is(reasons[1], ErrWrongLength) {
+		t.Errorf("hook called with %v, want [%v %v]", reasons, ErrEncode, ErrWrongLength)
+	}
+}
+
+func TestOnDecodeError_NilNoAlloc(t *testing.T) {
+	bad := []byte("11111111111111111111111111111110")
+	var out [32]byte
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = Decode32Checked(&out, bad, false)
+	})
+	if allocs != 0 {
+		t.Errorf("failed decode allocated %v times, want 0", allocs)
+	}
+}
+
+func TestLengthError(t *testing.T) {
+	for _, test := range []struct {
+		n    int
+		want string
+		hint bool
+	}{
+		{31, "31 characters, expected 32–44 (off by 1)", true},
+		{45, "45 characters, expected 32–44 (off by 1)", true},
+		{10, "10 characters, expected 32–44", false},
+	} {
+		_, err := ParsePubkey(strings.Repeat("2", test.n))
+		if !errors.Is(err, ErrWrongLength) {
+			t.Fatalf("ParsePubkey(%d chars) error = %v, want ErrWrongLength", test.n, err)
+		}
+		var lenErr *LengthError
+		if !errors.As(err, &lenErr) || lenErr.Len != test.n {
+			t.Fatalf("ParsePubkey(%d chars) error = %#v, want LengthError", test.n, err)
+		}
+		msg := err.Error()
+		if !strings.Contains(msg, test.want) {
+			t.Errorf("error %q does not contain %q", msg, test.want)
+		}
+		if strings.Contains(msg, "off by") != test.hint {
+			t.Errorf("error %q: hint = %t, want %t", msg, !test.hint, test.hint)
+		}
+	}
+}
+
+func TestEncode32Runes(t *testing.T) {
+	f := func(in [32]byte, zeros uint8) bool {
+		copy(in[:], make([]byte, int(zeros)%33))
+		return string(Encode32Runes(in)) == Encode(in[:])
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+	for _, test := range testVector32 {
+		var in [32]byte
+		hex.Decode(in[:], []byte(test.hex))
+		if got := string(Encode32Runes(in)); got != test.b58 {
+			t.Errorf("Encode32Runes(%s) = %s, want %s", test.hex, got, test.b58)
+		}
+	}
+}
+
+func TestMatchesTruncated(t *testing.T) {
+	var key [32]byte
+	hex.Decode(key[:], []byte(testVector32[5].hex)) // 4JqFY5UL...18Yr8fC
+
+	for _, test := range []struct {
+		prefix, suffix string
+		want           bool
+	}{
+		{"4JqF", "r8fC", true},
+		{"", "", true},
+		{testVector32[5].b58, "", true},
+		{"4JqF", "r8fD", false},
+		{"4JqG", "r8fC", false},
+		{"4Jq0", "", false},
+		{"", "r8f0", false},
+	} {
+		if got := MatchesTruncated(key, test.prefix, test.suffix); got != test.want {
+			t.Errorf("MatchesTruncated(%q, %q) = %t, want %t", test.prefix, test.suffix, got, test.want)
+		}
+	}
+}
+
+func TestEncode32Fixed(t *testing.T) {
+	f := func(in [32]byte, zeros uint8) bool {
+		copy(in[:], make([]byte, int(zeros)%33))
+		s := Encode32Fixed(in)
+		if len(s) != 44 || !strings.HasSuffix(s, Encode(in[:])) {
+			return false
+		}
+		out, canonical, err := Decode32Full([]byte(s))
+		return err == nil && out == in && canonical == (EncodedLen32(in) == 44)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+
+	if got, want := Encode32Fixed([32]byte{}), strings.Repeat("1", 44); got != want {
+		t.Errorf("Encode32Fixed(0) = %s, want %s", got, want)
+	}
+	var key [32]byte
+	hex.Decode(key[:], []byte(testVector32[2].hex))
+	if got, want := Encode32Fixed(key), strings.Repeat("1", 12)+testVector32[2].b58; got != want {
+		t.Errorf("Encode32Fixed(%s) = %s, want %s", testVector32[2].hex, got, want)
+	}
+}
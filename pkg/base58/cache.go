@@ -0,0 +1,76 @@
+package base58
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DecodeCache memoizes the results of decoding 32-byte values, for
+// callers that decode the same strings, such as well-known program
+// IDs, over and over.
+//
+// The cache holds at most a fixed number of successfully decoded
+// strings and evicts the least recently used one when full.  Invalid
+// input is never cached.  A DecodeCache is safe for concurrent use.
+type DecodeCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	lru     list.List // of *cacheEntry, most recently used first
+}
+
+type cacheEntry struct {
+	s   string
+	out [32]byte
+}
+
+// NewDecodeCache returns a DecodeCache holding up to size entries.
+// NewDecodeCache panics if size is not positive.
+func NewDecodeCache(size int) *DecodeCache {
+	if size <= 0 {
+		panic("base58: non-positive decode cache size")
+	}
+	return &DecodeCache{
+		size:    size,
+		entries: make(map[string]*list.Element, size),
+	}
+}
+
+// Decode32 is like the package-level Decode32, but looks s up in the
+// cache first and caches the result of a successful decode.
+func (c *DecodeCache) Decode32(s string) ([32]byte, bool) {
+	c.mu.Lock()
+	if e, ok := c.entries[s]; ok {
+		c.lru.MoveToFront(e)
+		out := e.Value.(*cacheEntry).out
+		c.mu.Unlock()
+		return out, true
+	}
+	c.mu.Unlock()
+
+	var out [32]byte
+	if !Decode32(&out, []byte(s)) {
+		return out, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[s]; ok {
+		// Added by a concurrent caller in the meantime.
+		return out, true
+	}
+	if c.lru.Len() >= c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).s)
+	}
+	c.entries[s] = c.lru.PushFront(&cacheEntry{s: s, out: out})
+	return out, true
+}
+
+// Len returns the number of cached entries.
+func (c *DecodeCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
@@ -0,0 +1,78 @@
+package base58
+
+import (
+	"encoding/hex"
+	"sync"
+	"testing"
+)
+
+func TestDecodeCache(t *testing.T) {
+	c := NewDecodeCache(2)
+	for _, test := range testVector32[:3] {
+		for i := 0; i < 2; i++ {
+			out, ok := c.Decode32(test.b58)
+			if !ok || hex.EncodeToString(out[:]) != test.hex {
+				t.Errorf("Decode32(%s) = %x, %t", test.b58, out, ok)
+			}
+		}
+	}
+	if n := c.Len(); n != 2 {
+		t.Errorf("Len() = %d, want 2", n)
+	}
+	// The first vector was evicted as the least recently used.
+	c.mu.Lock()
+	_, cached := c.entries[testVector32[0].b58]
+	c.mu.Unlock()
+	if cached {
+		t.Error("least recently used entry was not evicted")
+	}
+
+	if _, ok := c.Decode32("0"); ok {
+		t.Error("Decode32 accepted invalid input")
+	}
+	if n := c.Len(); n != 2 {
+		t.Errorf("Len() = %d after invalid input, want 2", n)
+	}
+}
+
+func TestDecodeCache_Concurrent(t *testing.T) {
+	c := NewDecodeCache(3)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				test := testVector32[(g+i)%len(testVector32)]
+				out, ok := c.Decode32(test.b58)
+				if !ok || hex.EncodeToString(out[:]) != test.hex {
+					t.Errorf("Decode32(%s) = %x, %t", test.b58, out, ok)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if n := c.Len(); n > 3 {
+		t.Errorf("Len() = %d, want at most 3", n)
+	}
+}
+
+func BenchmarkDecodeCache(b *testing.B) {
+	s := testVector32[5].b58
+	b.Run("Uncached", func(b *testing.B) {
+		enc := []byte(s)
+		var out [32]byte
+		for i := 0; i < b.N; i++ {
+			Decode32(&out, enc)
+		}
+	})
+	b.Run("Hit", func(b *testing.B) {
+		c := NewDecodeCache(16)
+		c.Decode32(s)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			c.Decode32(s)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package base58
+
+import (
+	"crypto/sha256"
+	"strings"
+)
+
+// EncodeWithShortChecksum returns the base58 encoding of in followed by
+// '#' and a checksum of checksumChars base58 characters, such as
+// "4JqFY5UL9nx2H16KoEGCe7XfyzYiXawQyRhZA18Yr8fC#d3Vq".
+//
+// The checksum is the tail of the base58 encoded SHA-256 digest of in.
+// It helps users notice transcription errors when comparing keys by
+// eye, but offers no protection against deliberately chosen keys, and
+// is unrelated to Base58Check.  checksumChars must be between 1 and 32;
+// EncodeWithShortChecksum panics otherwise.
+func EncodeWithShortChecksum(in [32]byte, checksumChars int) string {
+	if checksumChars < 1 || checksumChars > 32 {
+		panic("base58: checksum length out of range")
+	}
+	return Encode(in[:]) + "#" + shortChecksum(in, checksumChars)
+}
+
+// VerifyShortChecksum decodes a key with a checksum as produced by
+// EncodeWithShortChecksum, of any checksum length.  It returns false if
+// the key does not decode or does not match the checksum.
+func VerifyShortChecksum(s string) (Pubkey, bool) {
+	i := strings.LastIndexByte(s, '#')
+	if i < 0 {
+		return Pubkey{}, false
+	}
+	key, sum := s[:i], s[i+1:]
+	if len(sum) < 1 || len(sum) > 32 {
+		return Pubkey{}, false
+	}
+	var p Pubkey
+	if !Decode32((*[32]byte)(&p), []byte(key)) {
+		return Pubkey{}, false
+	}
+	if shortChecksum(p, len(sum)) != sum {
+		return Pubkey{}, false
+	}
+	return p, true
+}
+
+func shortChecksum(in [32]byte, n int) string {
+	digest := sha256.Sum256(in[:])
+	var out [44]byte
+	enc := out[:Encode32(&out, digest)]
+	return string(enc[len(enc)-n:])
+}
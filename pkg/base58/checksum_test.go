@@ -0,0 +1,47 @@
+package base58
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestShortChecksum(t *testing.T) {
+	var key [32]byte
+	hex.Decode(key[:], []byte(testVector32[5].hex))
+
+	s := EncodeWithShortChecksum(key, 4)
+	if !strings.HasPrefix(s, testVector32[5].b58+"#") || len(s) != len(testVector32[5].b58)+5 {
+		t.Fatalf("EncodeWithShortChecksum() = %s", s)
+	}
+	p, ok := VerifyShortChecksum(s)
+	if !ok || p != key {
+		t.Errorf("VerifyShortChecksum(%s) = %s, %t", s, p, ok)
+	}
+
+	// A longer checksum extends the shorter one.
+	if long := EncodeWithShortChecksum(key, 8); !strings.HasSuffix(long, s[len(s)-4:]) {
+		t.Errorf("EncodeWithShortChecksum(8) = %s, does not end like %s", long, s)
+	}
+
+	// Corrupting either the key or the checksum is detected.
+	corrupt := func(s string, i int) string {
+		b := []byte(s)
+		if b[i] == '2' {
+			b[i] = '3'
+		} else {
+			b[i] = '2'
+		}
+		return string(b)
+	}
+	for _, bad := range []string{
+		corrupt(s, 5),
+		corrupt(s, len(s)-1),
+		testVector32[5].b58,
+		testVector32[5].b58 + "#",
+	} {
+		if _, ok := VerifyShortChecksum(bad); ok {
+			t.Errorf("VerifyShortChecksum(%s) accepted corrupted input", bad)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package base58
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Codec decodes base58 strings using scratch space owned by the Codec
+// instead of the goroutine stack.
+//
+// The package-level decoders keep a few hundred bytes of intermediate
+// state in stack arrays.  On targets where stack growth is expensive
+// (notably GOOS=js/wasm), decoding in a loop repeatedly grows and
+// shrinks the stack.  A Codec allocated once with NewCodec keeps that
+// state on the heap and reuses it for every call.
+//
+// A Codec is not safe for concurrent use.
+type Codec struct {
+	s32 decodeScratch32
+	s64 decodeScratch64
+}
+
+// NewCodec allocates a Codec.
+func NewCodec() *Codec {
+	return new(Codec)
+}
+
+// Decode32 is like the package-level Decode32.
+func (c *Codec) Decode32(out *[32]byte, encoded []byte) (ok bool) {
+	return decode32(out, encoded, &c.s32)
+}
+
+// Decode64 is like the package-level Decode64.
+func (c *Codec) Decode64(out *[64]byte, encoded []byte) (ok bool) {
+	return decode64(out, encoded, &c.s64)
+}
+
+// DecodeAppend decodes a base58 encoded 32 or 64 byte value and appends
+// it to dst, returning the extended slice.  The value size is chosen
+// from the length of src.
+//
+// dst grows as by append, so the returned slice may alias dst, and
+// appending in a loop reallocates only occasionally.  Passing dst[:0]
+// reuses the same memory across calls.  On failure, dst is returned
+// unchanged and its spare capacity is left untouched.
+func (c *Codec) DecodeAppend(dst []byte, src []byte) ([]byte, error) {
+	out, err := c.decodeAppend(dst, src)
+	if err != nil {
+		return out, decodeFailed(err)
+	}
+	return out, nil
+}
+
+func (c *Codec) decodeAppend(dst []byte, src []byte) ([]byte, error) {
+	var size int
+	switch {
+	case checkInputLen(len(src), 32, 44) == nil:
+		size = 32
+	case checkInputLen(len(src), 64, 88) == nil:
+		size = 64
+	default:
+		return dst, ErrWrongLength
+	}
+
+	var buf [64]byte
+	var ok bool
+	if size == 32 {
+		ok = c.Decode32((*[32]byte)(buf[:32]), src)
+	} else {
+		ok = c.Decode64(&buf, src)
+	}
+	if !ok {
+		return dst, ErrEncode
+	}
+	return append(dst, buf[:size]...), nil
+}
+
+// DecodeGrouped decodes a 32 or 64 byte value displayed in groups,
+// such as "So111 11111 ...", after removing every occurrence of sep.
+//
+// sep must not be a base58 digit, since removing it would then change
+// the value.  Input longer than MaxDecodeInputLen, separators included,
+// is rejected before it is inspected.
+func DecodeGrouped(s string, sep rune) ([]byte, error) {
+	if strings.ContainsRune(alphabet, sep) {
+		return nil, fmt.Errorf("separator %q is a base58 digit", sep)
+	}
+	if len(s) > MaxDecodeInputLen {
+		return nil, decodeFailed(ErrWrongLength)
+	}
+	var c Codec
+	return c.DecodeAppend(nil, []byte(strings.ReplaceAll(s, string(sep), "")))
+}
@@ -0,0 +1,186 @@
+package base58
+
+import "fmt"
+
+// Encoding is a base58 encoding with a custom alphabet.
+//
+// It uses the same conversion as the package-level functions and
+// translates characters between its alphabet and the standard one.
+type Encoding struct {
+	alphabet string
+	lut      []byte // maps (character value - offset) to [0, 58)
+	offset   byte
+	std      bool // alphabet is the standard one, no translation needed
+}
+
+// stdEncoding is the Encoding of the standard alphabet, built from the
+// precomputed inverse LUT.
+var stdEncoding = &Encoding{
+	alphabet: alphabet,
+	lut:      inverseLUT[:],
+	offset:   inverseLUTOffset,
+	std:      true,
+}
+
+// NewEncoding returns an Encoding for the given alphabet, which must
+// consist of 58 distinct ASCII characters.  The first character is the
+// zero digit, which also represents leading zero bytes.
+//
+// The characters may lie anywhere in the ASCII range.  The standard
+// alphabet returns a shared Encoding without building any tables.
+//
+// NewEncoding returns an error if the alphabet is malformed, or if the
+// resulting Encoding fails to round-trip a few probe values.
+func NewEncoding(alphabet string) (*Encoding, error) {
+	if alphabet == stdEncoding.alphabet {
+		return stdEncoding, nil
+	}
+	if len(alphabet) != 58 {
+		return nil, fmt.Errorf("base58 alphabet has %d characters, want 58", len(alphabet))
+	}
+	lo, hi := byte(0x7F), byte(0)
+	for i := 0; i < len(alphabet); i++ {
+		c := alphabet[i]
+		if c >= 0x80 {
+			return nil, fmt.Errorf("base58 alphabet contains non-ASCII byte %#x at offset %d", c, i)
+		}
+		if c < lo {
+			lo = c
+		}
+		if c > hi {
+			hi = c
+		}
+	}
+	e := &Encoding{
+		alphabet: alphabet,
+		lut:      make([]byte, int(hi-lo)+1),
+		offset:   lo,
+	}
+	for i := range e.lut {
+		e.lut[i] = invalidChar
+	}
+	for i := 0; i < len(alphabet); i++ {
+		idx := alphabet[i] - lo
+		if e.lut[idx] != invalidChar {
+			return nil, fmt.Errorf("base58 alphabet contains %q twice", alphabet[i])
+		}
+		e.lut[idx] = byte(i)
+	}
+	if err := e.selfCheck(); err != nil {
+		return nil, fmt.Errorf("base58 alphabet: %w", err)
+	}
+	return e, nil
+}
+
+// selfCheck encodes and decodes probe values of both sizes, including
+// the extremes, and reports the first one that does not round-trip.
+func (e *Encoding) selfCheck() error {
+	var probes [3][64]byte
+	for i := range probes[2] {
+		probes[1][i] = byte(i + 1)
+		probes[2][i] = 0xFF
+	}
+	for _, probe := range probes {
+		in32 := *(*[32]byte)(probe[:32])
+		var enc32 [44]byte
+		var dec32 [32]byte
+		if n := e.Encode32(&enc32, in32); !e.Decode32(&dec32, enc32[:n]) || dec32 != in32 {
+			return fmt.Errorf("self-check failed for 32-byte value %x", in32)
+		}
+		var enc64 [88]byte
+		var dec64 [64]byte
+		if n := e.Encode64(&enc64, probe); !e.Decode64(&dec64, enc64[:n]) || dec64 != probe {
+			return fmt.Errorf("self-check failed for 64-byte value %x", probe)
+		}
+	}
+	return nil
+}
+
+// Alphabet returns the alphabet of the encoding, ordered by digit value.
+func (e *Encoding) Alphabet() string {
+	return e.alphabet
+}
+
+// digit returns the value of c in the alphabet, or invalidChar.
+func (e *Encoding) digit(c byte) byte {
+	if c < e.offset || int(c-e.offset) >= len(e.lut) {
+		return invalidChar
+	}
+	return e.lut[c-e.offset]
+}
+
+// fromStd translates standard base58 characters to the alphabet in place.
+func (e *Encoding) fromStd(b []byte) {
+	if e.std {
+		return
+	}
+	for i, c := range b {
+		b[i] = e.alphabet[inverseLUT[c-inverseLUTOffset]]
+	}
+}
+
+// toStd translates characters of the alphabet to standard base58.
+func (e *Encoding) toStd(dst, src []byte) bool {
+	for i, c := range src {
+		d := e.digit(c)
+		if d == invalidChar {
+			return false
+		}
+		dst[i] = alphabet[d]
+	}
+	return true
+}
+
+// Encode32 is like the package-level Encode32.
+func (e *Encoding) Encode32(out *[44]byte, in [32]byte) uint {
+	n := Encode32(out, in)
+	e.fromStd(out[:n])
+	return n
+}
+
+// Encode64 is like the package-level Encode64.
+func (e *Encoding) Encode64(out *[88]byte, in [64]byte) uint {
+	n := Encode64(out, in)
+	e.fromStd(out[:n])
+	return n
+}
+
+// Decode32 is like the package-level Decode32.
+func (e *Encoding) Decode32(out *[32]byte, encoded []byte) (ok bool) {
+	if checkInputLen(len(encoded), 32, 44) != nil {
+		return false
+	}
+	if e.std {
+		return Decode32(out, encoded)
+	}
+	var std [44]byte
+	if !e.toStd(std[:], encoded) {
+		return false
+	}
+	return Decode32(out, std[:len(encoded)])
+}
+
+// Decode64 is like the package-level Decode64.
+func (e *Encoding) Decode64(out *[64]byte, encoded []byte) (ok bool) {
+	if checkInputLen(len(encoded), 64, 88) != nil {
+		return false
+	}
+	if e.std {
+		return Decode64(out, encoded)
+	}
+	var std [88]byte
+	if !e.toStd(std[:], encoded) {
+		return false
+	}
+	return Decode64(out, std[:len(encoded)])
+}
+
+// Encode is like the package-level Encode.
+func (e *Encoding) Encode(buf []byte) string {
+	if e.std {
+		return Encode(buf)
+	}
+	out := []byte(Encode(buf))
+	e.fromStd(out)
+	return string(out)
+}
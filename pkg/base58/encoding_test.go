@@ -0,0 +1,134 @@
+package base58
+
+import (
+	"encoding/hex"
+	"testing"
+	"testing/quick"
+)
+
+// lowerAlphabet is a base58 alphabet without uppercase letters, as used
+// by some legacy systems.
+const lowerAlphabet = "0123456789abcdefghijklmnopqrstuvwxyz!#$%&()*+,-./:;<=>?@[]"
+
+// newEncoding returns the Encoding of alphabet, failing the test on
+// error.
+func newEncoding(t *testing.T, alphabet string) *Encoding {
+	t.Helper()
+	enc, err := NewEncoding(alphabet)
+	if err != nil {
+		t.Fatalf("NewEncoding(%q) = %v", alphabet, err)
+	}
+	return enc
+}
+
+func TestEncoding_Std(t *testing.T) {
+	enc := newEncoding(t, alphabet)
+	for _, test := range testVector32 {
+		var in [32]byte
+		hex.Decode(in[:], []byte(test.hex))
+		if got := enc.Encode(in[:]); got != test.b58 {
+			t.Errorf("Encode(%s) = %s, want %s", test.hex, got, test.b58)
+		}
+	}
+
+	f := func(in [64]byte) bool {
+		var want, got [88]byte
+		n := Encode64(&want, in)
+		if enc.Encode64(&got, in) != n || got != want {
+			return false
+		}
+		var dec [64]byte
+		return enc.Decode64(&dec, got[:n]) && dec == in
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+
+	if newEncoding(t, alphabet) != enc {
+		t.Error("NewEncoding did not reuse the standard Encoding")
+	}
+	if n := testing.AllocsPerRun(10, func() { NewEncoding(alphabet) }); n != 0 {
+		t.Errorf("NewEncoding(alphabet) allocated %v times", n)
+	}
+}
+
+func TestEncoding_Lowercase(t *testing.T) {
+	enc := newEncoding(t, lowerAlphabet)
+
+	var out [44]byte
+	if n := enc.Encode32(&out, [32]byte{}); string(out[:n]) != "00000000000000000000000000000000" {
+		t.Errorf("Encode32(0) = %s", out[:n])
+	}
+
+	f32 := func(in [32]byte) bool {
+		var out [44]byte
+		n := enc.Encode32(&out, in)
+		for _, c := range out[:n] {
+			if c >= 'A' && c <= 'Z' {
+				return false
+			}
+		}
+		var dec [32]byte
+		return enc.Decode32(&dec, out[:n]) && dec == in
+	}
+	if err := quick.Check(f32, nil); err != nil {
+		t.Error(err)
+	}
+	f64 := func(in [64]byte) bool {
+		var out [88]byte
+		n := enc.Encode64(&out, in)
+		var dec [64]byte
+		return enc.Decode64(&dec, out[:n]) && dec == in
+	}
+	if err := quick.Check(f64, nil); err != nil {
+		t.Error(err)
+	}
+
+	// Standard characters outside the alphabet are rejected.
+	var dec [32]byte
+	if enc.Decode32(&dec, []byte(testVector32[5].b58)) {
+		t.Error("Decode32 accepted uppercase input")
+	}
+}
+
+func TestNewEncoding_Invalid(t *testing.T) {
+	for _, a := range []string{
+		alphabet[:57],
+		alphabet[:57] + "1",
+		alphabet[:57] + "\x80",
+	} {
+		if _, err := NewEncoding(a); err == nil {
+			t.Errorf("NewEncoding(%q) succeeded", a)
+		}
+	}
+}
+
+func TestEncoding_Alphabet(t *testing.T) {
+	for _, a := range []string{alphabet, lowerAlphabet} {
+		if got := newEncoding(t, a).Alphabet(); got != a {
+			t.Errorf("Alphabet() = %q, want %q", got, a)
+		}
+	}
+}
+
+func TestEncoding_SelfCheck(t *testing.T) {
+	if err := newEncoding(t, lowerAlphabet).selfCheck(); err != nil {
+		t.Errorf("selfCheck() = %v", err)
+	}
+
+	// A corrupted LUT fails the self-check.
+	enc := newEncoding(t, lowerAlphabet)
+	enc.lut = append([]byte(nil), enc.lut...)
+	enc.lut['1'-enc.offset], enc.lut['2'-enc.offset] = enc.lut['2'-enc.offset], enc.lut['1'-enc.offset]
+	if err := enc.selfCheck(); err == nil {
+		t.Error("selfCheck() accepted a corrupted LUT")
+	}
+
+	// A duplicate character fails at construction.
+	dup := lowerAlphabet[:57] + "0"
+	if enc, err := NewEncoding(dup); err == nil || enc != nil {
+		t.Errorf("NewEncoding(%q) = %v, %v, want error", dup, enc, err)
+	} else if want := `base58 alphabet contains '0' twice`; err.Error() != want {
+		t.Errorf("NewEncoding(%q) error = %q, want %q", dup, err, want)
+	}
+}
@@ -0,0 +1,23 @@
+// Command gentables writes the radix conversion tables of package base58.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"go.firedancer.io/radiance/pkg/base58/internal/gentables"
+)
+
+func main() {
+	out := flag.String("o", "tables.go", "output file")
+	flag.Parse()
+
+	src, err := gentables.Source("base58", []int{32, 64})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
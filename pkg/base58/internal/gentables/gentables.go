@@ -0,0 +1,104 @@
+// Package gentables computes the radix conversion tables of package base58.
+//
+// An n-byte value is represented as B = n/4 limbs of base 2^32 and as
+// I limbs of base 58^5, where I is the smallest count such that
+// 58^(5*I) >= 2^(8*n).
+package gentables
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"math/big"
+)
+
+// Sizes returns the number of base 2^32 and base 58^5 limbs of an n-byte value.
+func Sizes(n int) (binarySz, intermediateSz int) {
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(8*n))
+	r1 := big.NewInt(58 * 58 * 58 * 58 * 58)
+	p := big.NewInt(1)
+	for p.Cmp(limit) < 0 {
+		p.Mul(p, r1)
+		intermediateSz++
+	}
+	return n / 4, intermediateSz
+}
+
+// EncTable returns the table of unique values less than 58^5 such that:
+//
+//	2^(32*(B-1-j)) = sum_k table[j][k]*58^(5*(I-2-k))
+func EncTable(n int) [][]uint32 {
+	binarySz, intermediateSz := Sizes(n)
+	table := make([][]uint32, binarySz)
+	for j := range table {
+		v := new(big.Int).Lsh(big.NewInt(1), uint(32*(binarySz-1-j)))
+		table[j] = digits(v, big.NewInt(58*58*58*58*58), intermediateSz-1)
+	}
+	return table
+}
+
+// DecTable returns the table of unique values less than 2^32 such that:
+//
+//	58^(5*(I-1-j)) = sum_k table[j][k]*2^(32*(B-1-k))
+func DecTable(n int) [][]uint32 {
+	binarySz, intermediateSz := Sizes(n)
+	table := make([][]uint32, intermediateSz)
+	for j := range table {
+		v := new(big.Int).Exp(big.NewInt(58), big.NewInt(int64(5*(intermediateSz-1-j))), nil)
+		table[j] = digits(v, new(big.Int).Lsh(big.NewInt(1), 32), binarySz)
+	}
+	return table
+}
+
+// digits returns the cnt most significant first digits of v in the given base.
+func digits(v, base *big.Int, cnt int) []uint32 {
+	out := make([]uint32, cnt)
+	v = new(big.Int).Set(v)
+	rem := new(big.Int)
+	for k := cnt - 1; k >= 0; k-- {
+		v.DivMod(v, base, rem)
+		out[k] = uint32(rem.Uint64())
+	}
+	if v.Sign() != 0 {
+		panic("value does not fit")
+	}
+	return out
+}
+
+// Source returns formatted Go source declaring the tables for each
+// given byte size in package pkg.
+func Source(pkg string, sizes []int) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by gentables; DO NOT EDIT.\n\npackage %s\n", pkg)
+	for _, n := range sizes {
+		binarySz, intermediateSz := Sizes(n)
+		fmt.Fprintf(&buf, `
+// encTable%[1]d contains the unique values less than 58^5 such that:
+//
+//	2^(32*(%[2]d-j)) = sum_k table[j][k]*58^(5*(%[3]d-k))
+`, n, binarySz-1, intermediateSz-2)
+		writeTable(&buf, fmt.Sprintf("encTable%d", n), EncTable(n))
+		fmt.Fprintf(&buf, `
+// decTable%[1]d contains the unique values less than 2^32 such that:
+//
+//	58^(5*(%[3]d-j)) = sum_k table[j][k]*2^(32*(%[2]d-k))
+`, n, binarySz-1, intermediateSz-1)
+		writeTable(&buf, fmt.Sprintf("decTable%d", n), DecTable(n))
+	}
+	return format.Source(buf.Bytes())
+}
+
+func writeTable(buf *bytes.Buffer, name string, table [][]uint32) {
+	fmt.Fprintf(buf, "var %s = [%d][%d]uint32{\n", name, len(table), len(table[0]))
+	for _, row := range table {
+		buf.WriteString("{")
+		for k, v := range row {
+			if k > 0 {
+				buf.WriteString(", ")
+			}
+			fmt.Fprint(buf, v)
+		}
+		buf.WriteString("},\n")
+	}
+	buf.WriteString("}\n")
+}
@@ -0,0 +1,17 @@
+package base58
+
+import "testing"
+
+func TestEncodeOrName32(t *testing.T) {
+	if got := EncodeOrName32([32]byte{}); got != "System Program" {
+		t.Errorf("EncodeOrName32(0) = %s", got)
+	}
+	token, _ := ParsePubkey("TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA")
+	if got := EncodeOrName32(token); got != "Token Program" {
+		t.Errorf("EncodeOrName32(token) = %s", got)
+	}
+	unknown, _ := ParsePubkey(testVector32[5].b58)
+	if got := EncodeOrName32(unknown); got != testVector32[5].b58 {
+		t.Errorf("EncodeOrName32(unknown) = %s", got)
+	}
+}
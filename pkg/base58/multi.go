@@ -0,0 +1,46 @@
+package base58
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// ErrAmbiguous is returned by DecodeMulti for input that is neither
+// valid base58 nor recognizably base64url.
+var ErrAmbiguous = errors.New("ambiguous or invalid encoding")
+
+// DecodeMulti decodes s as either base58 or base64url.
+//
+// Input of 32 to 88 base58 characters is decoded as a 32 or 64 byte
+// base58 value.  Otherwise, input of 43 or 44 characters containing at
+// least one of '-', '_' or '=' is decoded as a 32-byte base64url value,
+// with or without padding.  Anything else returns ErrAmbiguous.
+func DecodeMulti(s string) ([]byte, error) {
+	if checkInputLen(len(s), 32, 88) == nil && validChars([]byte(s)) {
+		var c Codec
+		if out, err := c.decodeAppend(nil, []byte(s)); err == nil {
+			return out, nil
+		}
+	}
+	if (len(s) == 43 || len(s) == 44) && strings.ContainsAny(s, "-_=") {
+		enc := base64.RawURLEncoding
+		if len(s) == 44 {
+			enc = base64.URLEncoding
+		}
+		out, err := enc.DecodeString(s)
+		if err == nil && len(out) == 32 {
+			return out, nil
+		}
+	}
+	return nil, decodeFailed(ErrAmbiguous)
+}
+
+// EncodeBase64URL32 returns the unpadded base64url encoding of in, the
+// 43-character form accepted by DecodeMulti.  It is cheaper to compute
+// than the base58 encoding, for callers free to choose the format.
+func EncodeBase64URL32(in [32]byte) string {
+	var out [43]byte
+	base64.RawURLEncoding.Encode(out[:], in[:])
+	return string(out[:])
+}
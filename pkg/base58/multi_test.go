@@ -0,0 +1,72 @@
+package base58
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestDecodeMulti(t *testing.T) {
+	typical, _ := hex.DecodeString(testVector32[5].hex)
+	max, _ := hex.DecodeString(testVector32[3].hex)
+	max64, _ := hex.DecodeString(testVector64[5].hex)
+	for _, test := range []struct {
+		in   string
+		want []byte
+	}{
+		{testVector32[5].b58, typical},
+		{"MSR93sRzY6dsKmHrMucCM6XfGt3wxGq22GlllBMlcRs=", typical},
+		{testVector32[3].b58, max},
+		{"__________________________________________8", max},
+		{"__________________________________________8=", max},
+		{testVector64[5].b58, max64},
+	} {
+		got, err := DecodeMulti(test.in)
+		if err != nil || !bytes.Equal(got, test.want) {
+			t.Errorf("DecodeMulti(%s) = %x, %v, want %x", test.in, got, err, test.want)
+		}
+	}
+
+	for _, in := range []string{
+		"",
+		"MSR93sRzY6dsKmHrMucCM6XfGt3wxGq22GlllBMlcRs",   // base64 without marker
+		"MSR93sRzY6dsKmHrMucCM6XfGt3wxGq22GlllBMlcRs==", // too long
+		"zzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzz",  // base58 overflow
+	} {
+		if _, err := DecodeMulti(in); err != ErrAmbiguous {
+			t.Errorf("DecodeMulti(%s) error = %v, want ErrAmbiguous", in, err)
+		}
+	}
+}
+
+func TestEncodeBase64URL32(t *testing.T) {
+	var in [32]byte
+	hex.Decode(in[:], []byte(testVector32[3].hex))
+	s := EncodeBase64URL32(in)
+	if s != "__________________________________________8" {
+		t.Errorf("EncodeBase64URL32(%x) = %s", in, s)
+	}
+	got, err := DecodeMulti(s)
+	if err != nil || !bytes.Equal(got, in[:]) {
+		t.Errorf("DecodeMulti(%s) = %x, %v, want %x", s, got, err, in)
+	}
+}
+
+// BenchmarkEncodeString32 compares encoding keys to strings as base58
+// and as base64url.
+func BenchmarkEncodeString32(b *testing.B) {
+	var in [32]byte
+	hex.Decode(in[:], []byte(testVector32[5].hex))
+	b.Run("Base58", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Encode(in[:])
+		}
+	})
+	b.Run("Base64URL", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			EncodeBase64URL32(in)
+		}
+	})
+}
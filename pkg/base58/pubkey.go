@@ -0,0 +1,214 @@
+package base58
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"filippo.io/edwards25519"
+)
+
+// ErrNotOnCurve is returned when a key is required to be a valid
+// ed25519 public key but is not a point on the curve.
+var ErrNotOnCurve = errors.New("not an ed25519 curve point")
+
+// ErrNonASCII is returned when parsing input that contains non-ASCII
+// characters, such as fullwidth lookalikes of base58 digits.
+var ErrNonASCII = errors.New("non-ASCII character")
+
+// Errors returned by ParsePubkeyStrict.  Length errors match
+// ErrWrongLength.
+var (
+	ErrInvalidChar  = errors.New("invalid base58 character")
+	ErrOverflow     = errors.New("base58 value exceeds 32 bytes")
+	ErrNonCanonical = errors.New("non-canonical base58 encoding")
+)
+
+// ErrNotJSONString is returned by DecodeJSON32 for JSON values other
+// than strings.
+var ErrNotJSONString = errors.New("JSON value is not a string")
+
+// Pubkey is a 32-byte public key.
+type Pubkey [32]byte
+
+// ParsePubkey decodes a base58 public key.
+//
+// Input containing non-ASCII characters is rejected with ErrNonASCII
+// before decoding.  No Unicode normalization is attempted, as mapping
+// lookalike characters onto the alphabet would silently accept keys
+// the user never typed.
+func ParsePubkey(s string) (Pubkey, error) {
+	p, err := parsePubkey(s)
+	if err != nil {
+		return Pubkey{}, decodeFailed(err)
+	}
+	return p, nil
+}
+
+func parsePubkey(s string) (Pubkey, error) {
+	if len(s) > MaxDecodeInputLen {
+		return Pubkey{}, inputLenError32(len(s))
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			r, _ := utf8.DecodeRuneInString(s[i:])
+			return Pubkey{}, fmt.Errorf("%w %q at offset %d", ErrNonASCII, r, i)
+		}
+	}
+	return decodeString32(s)
+}
+
+// DecodeInto decodes a base58 public key directly into dst.  On
+// failure, dst is zeroed and the error of ParsePubkey is returned.
+func DecodeInto(dst *Pubkey, s string) error {
+	p, err := ParsePubkey(s)
+	*dst = p
+	return err
+}
+
+// DecodeJSON32 decodes a base58 public key held in a JSON string
+// token, such as a field of a json.RawMessage, into out.  Escapes in
+// the string are resolved before decoding.  Tokens other than strings
+// fail with ErrNotJSONString.
+func DecodeJSON32(raw json.RawMessage, out *[32]byte) error {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || raw[0] != '"' {
+		return decodeFailed(ErrNotJSONString)
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return decodeFailed(err)
+	}
+	return DecodeInto((*Pubkey)(out), s)
+}
+
+// ParsePubkeyStrict decodes a base58 public key, reporting the reason
+// for any failure with a distinct error: a *LengthError for input of
+// the wrong length, ErrInvalidChar for characters outside the
+// alphabet, ErrOverflow for values of 2^256 or more, and
+// ErrNonCanonical for input whose leading '1' characters do not match
+// the leading zero bytes of the value.
+func ParsePubkeyStrict(s string) (Pubkey, error) {
+	if err := inputLenError32(len(s)); err != nil {
+		return Pubkey{}, decodeFailed(err)
+	}
+	for i := 0; i < len(s); i++ {
+		if !validChars([]byte{s[i]}) {
+			return Pubkey{}, decodeFailed(fmt.Errorf("%w %q at offset %d", ErrInvalidChar, s[i], i))
+		}
+	}
+	var p Pubkey
+	var scratch decodeScratch32
+	if !convert32((*[32]byte)(&p), []byte(s), &scratch) {
+		return Pubkey{}, decodeFailed(ErrOverflow)
+	}
+	if !isCanonical(p[:], []byte(s)) {
+		return Pubkey{}, decodeFailed(ErrNonCanonical)
+	}
+	return p, nil
+}
+
+// String returns the base58 encoding of the public key.
+func (p Pubkey) String() string {
+	return Encode(p[:])
+}
+
+// Bytes returns the public key as a byte slice.
+func (p Pubkey) Bytes() []byte {
+	return p[:]
+}
+
+// Array returns the public key as a byte array.
+func (p Pubkey) Array() [32]byte {
+	return p
+}
+
+// IsZero reports whether p is the all-zero key, which encodes to 32
+// '1' characters and is both the system program ID and the default
+// address.
+func (p Pubkey) IsZero() bool {
+	return p == Pubkey{}
+}
+
+// PubkeyFromBytes returns the public key held in b, which must be 32
+// bytes long.
+func PubkeyFromBytes(b []byte) (Pubkey, error) {
+	if len(b) != 32 {
+		return Pubkey{}, fmt.Errorf("%w: %d bytes, want 32", ErrWrongLength, len(b))
+	}
+	return Pubkey(b), nil
+}
+
+// DecodeKeys decodes each line into successive entries of dst, starting
+// at index 0, and returns the filled slice.  dst is only reallocated if
+// its capacity is insufficient, so a pooled slice can be passed back in
+// to decode without allocating.
+//
+// On failure, the keys decoded before the failing line are returned
+// along with an error naming its index.
+func DecodeKeys(lines [][]byte, dst []Pubkey) ([]Pubkey, error) {
+	dst = dst[:0]
+	for i, line := range lines {
+		var p Pubkey
+		if err := decode32Err((*[32]byte)(&p), line); err != nil {
+			return dst, decodeFailed(fmt.Errorf("key %d: %w", i, err))
+		}
+		dst = append(dst, p)
+	}
+	return dst, nil
+}
+
+// Decode32Checked decodes a base58 encoded public key.
+//
+// If requireOnCurve is set, the key must also be a valid compressed
+// ed25519 point, as is the case for keys backed by a keypair.
+// Program derived addresses are off-curve by construction, so callers
+// accepting them must not set requireOnCurve.
+func Decode32Checked(out *[32]byte, encoded []byte, requireOnCurve bool) error {
+	if err := decode32Err(out, encoded); err != nil {
+		return decodeFailed(err)
+	}
+	if requireOnCurve {
+		if _, err := new(edwards25519.Point).SetBytes(out[:]); err != nil {
+			return decodeFailed(ErrNotOnCurve)
+		}
+	}
+	return nil
+}
+
+// CompareDecoded32 decodes two base58 encoded 32-byte values and
+// compares them numerically, returning -1, 0, or +1.
+//
+// Sorting base58 strings as text does not sort them by value, since
+// encodings differ in length.
+func CompareDecoded32(a, b string) (int, error) {
+	x, err := decodeString32(a)
+	if err != nil {
+		return 0, decodeFailed(fmt.Errorf("decode %q: %w", a, err))
+	}
+	y, err := decodeString32(b)
+	if err != nil {
+		return 0, decodeFailed(fmt.Errorf("decode %q: %w", b, err))
+	}
+	return bytes.Compare(x[:], y[:]), nil
+}
+
+// decodeString32 decodes a 32-byte value, distinguishing length errors.
+func decodeString32(s string) (out [32]byte, err error) {
+	err = decode32Err(&out, []byte(s))
+	return out, err
+}
+
+// decode32Err is like Decode32, but returns a *LengthError or ErrEncode
+// on failure.  It does not call OnDecodeError.
+func decode32Err(out *[32]byte, encoded []byte) error {
+	if err := inputLenError32(len(encoded)); err != nil {
+		return err
+	}
+	if !Decode32(out, encoded) {
+		return ErrEncode
+	}
+	return nil
+}
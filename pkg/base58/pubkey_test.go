@@ -0,0 +1,260 @@
+package base58
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParsePubkey(t *testing.T) {
+	const key = "JEKNVnkbo3jma5nREBBJCDoXFVeKkD56V3xKrvRmWxFG"
+	p, err := ParsePubkey(key)
+	if err != nil {
+		t.Fatalf("ParsePubkey(%s) failed: %v", key, err)
+	}
+	if p.String() != key {
+		t.Errorf("ParsePubkey(%s) = %s", key, p)
+	}
+
+	// Fullwidth digits (U+FF11, U+FF12) look like '1' and '2'.
+	for _, s := range []string{
+		"１１１１１１１１１１１１１１１１１１１１１１１１１１１１１１１２",
+		"JEKNVnkbo3jma5nREBBJCDoXFVeKkD56V3xKrvRmWxF２",
+	} {
+		if _, err := ParsePubkey(s); !errors.Is(err, ErrNonASCII) {
+			t.Errorf("ParsePubkey(%s) = %v, want ErrNonASCII", s, err)
+		}
+	}
+
+	if _, err := ParsePubkey("0EKNVnkbo3jma5nREBBJCDoXFVeKkD56V3xKrvRmWxFG"); !errors.Is(err, ErrEncode) {
+		t.Errorf("ParsePubkey with invalid ASCII = %v, want ErrEncode", err)
+	}
+}
+
+func TestDecode32Checked(t *testing.T) {
+	// Public key of the ed25519 keypair with an all-zero seed.
+	const onCurve = "4zvwRjXUKGfvwnParsHAS3HuSVzV5cA4McphgmoCtajS"
+	// Program derived address of seed "radiance" under the system program.
+	const pda = "CQWgNv99nWeUGNhBUX1zJT3MZdQ1CRUivHLaiAHfD8Z"
+
+	var out [32]byte
+	for _, s := range []string{onCurve, pda} {
+		if err := Decode32Checked(&out, []byte(s), false); err != nil {
+			t.Errorf("Decode32Checked(%s, false) = %v", s, err)
+		}
+	}
+	if err := Decode32Checked(&out, []byte(onCurve), true); err != nil {
+		t.Errorf("Decode32Checked(%s, true) = %v", onCurve, err)
+	}
+	if err := Decode32Checked(&out, []byte(pda), true); !errors.Is(err, ErrNotOnCurve) {
+		t.Errorf("Decode32Checked(%s, true) = %v, want ErrNotOnCurve", pda, err)
+	}
+	if err := Decode32Checked(&out, []byte("1111"), true); !errors.Is(err, ErrWrongLength) {
+		t.Errorf("Decode32Checked(1111, true) = %v, want ErrWrongLength", err)
+	}
+}
+
+func TestCompareDecoded32(t *testing.T) {
+	// 58^43-1 and 58^43: the smaller value sorts last as text.
+	small := strings.Repeat("z", 43)
+	large := "2" + strings.Repeat("1", 43)
+	if small < large {
+		t.Fatal("test keys do not sort differently as text")
+	}
+
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{small, large, -1},
+		{large, small, 1},
+		{small, small, 0},
+		{"11111111111111111111111111111111", "11111111111111111111111111111112", -1},
+	}
+	for _, tc := range cases {
+		got, err := CompareDecoded32(tc.a, tc.b)
+		if err != nil {
+			t.Errorf("CompareDecoded32(%s, %s) failed: %v", tc.a, tc.b, err)
+		} else if got != tc.want {
+			t.Errorf("CompareDecoded32(%s, %s) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+
+	if _, err := CompareDecoded32(small, "1"); !errors.Is(err, ErrWrongLength) {
+		t.Errorf("CompareDecoded32 with short input = %v, want ErrWrongLength", err)
+	}
+	if _, err := CompareDecoded32(strings.Repeat("0", 32), small); !errors.Is(err, ErrEncode) {
+		t.Errorf("CompareDecoded32 with invalid input = %v, want ErrEncode", err)
+	}
+}
+
+func TestDecodeKeys(t *testing.T) {
+	lines := [][]byte{
+		[]byte("11111111111111111111111111111111"),
+		[]byte("4zvwRjXUKGfvwnParsHAS3HuSVzV5cA4McphgmoCtajS"),
+	}
+	keys, err := DecodeKeys(lines, make([]Pubkey, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0] != (Pubkey{}) || keys[1].String() != string(lines[1]) {
+		t.Errorf("DecodeKeys() = %v", keys)
+	}
+
+	lines = append(lines, []byte("0vwRjXUKGfvwnParsHAS3HuSVzV5cA4McphgmoCtajS"))
+	keys, err = DecodeKeys(lines, keys)
+	if !errors.Is(err, ErrEncode) || !strings.Contains(err.Error(), "key 2") {
+		t.Errorf("DecodeKeys() error = %v", err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("DecodeKeys() returned %d partial keys, want 2", len(keys))
+	}
+}
+
+func BenchmarkDecodeKeys(b *testing.B) {
+	lines := make([][]byte, 1024)
+	for i := range lines {
+		lines[i] = []byte(testVector32[len(testVector32)-1].b58)
+	}
+	dst := make([]Pubkey, 0, len(lines))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var err error
+		dst, err = DecodeKeys(lines, dst)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestPubkey_Conversions(t *testing.T) {
+	want := testVector32[5].b58
+	p, err := ParsePubkey(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fromSlice, err := PubkeyFromBytes(p.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var arr [32]byte = p.Array()
+	for _, in := range [][32]byte{p.Array(), [32]byte(fromSlice), arr} {
+		var out [44]byte
+		if n := Encode32(&out, in); string(out[:n]) != want {
+			t.Errorf("Encode32() = %s, want %s", out[:n], want)
+		}
+	}
+
+	if _, err := PubkeyFromBytes(make([]byte, 31)); !errors.Is(err, ErrWrongLength) {
+		t.Errorf("PubkeyFromBytes(31 bytes) error = %v", err)
+	}
+}
+
+func TestPubkey_IsZero(t *testing.T) {
+	zero, err := ParsePubkey("11111111111111111111111111111111")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !zero.IsZero() {
+		t.Errorf("%s.IsZero() = false", zero)
+	}
+	for _, test := range testVector32[1:] {
+		p, err := ParsePubkey(test.b58)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.IsZero() {
+			t.Errorf("%s.IsZero() = true", p)
+		}
+	}
+}
+
+func TestParsePubkeyStrict(t *testing.T) {
+	p, err := ParsePubkeyStrict(testVector32[5].b58)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.String() != testVector32[5].b58 {
+		t.Errorf("ParsePubkeyStrict() = %s, want %s", p, testVector32[5].b58)
+	}
+
+	for _, test := range []struct {
+		in  string
+		err error
+	}{
+		{"1111", ErrWrongLength},
+		{"0" + testVector32[5].b58[1:], ErrInvalidChar},
+		{"zzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzz", ErrOverflow},
+		// 0x01 followed by zeros has no leading zero byte to spare.
+		{"1" + Pubkey{1}.String(), ErrNonCanonical},
+	} {
+		if _, err := ParsePubkeyStrict(test.in); !errors.Is(err, test.err) {
+			t.Errorf("ParsePubkeyStrict(%s) error = %v, want %v", test.in, err, test.err)
+		}
+	}
+}
+
+func TestDecodeInto(t *testing.T) {
+	var p Pubkey
+	if err := DecodeInto(&p, testVector32[5].b58); err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(p[:]) != testVector32[5].hex {
+		t.Errorf("DecodeInto() = %x, want %s", p, testVector32[5].hex)
+	}
+
+	for _, test := range []struct {
+		in  string
+		err error
+	}{
+		{"1111", ErrWrongLength},
+		{"0" + testVector32[5].b58[1:], ErrEncode},
+		{"1" + Pubkey{1}.String(), ErrEncode},
+	} {
+		p := Pubkey{0xAA}
+		if err := DecodeInto(&p, test.in); !errors.Is(err, test.err) {
+			t.Errorf("DecodeInto(%s) error = %v, want %v", test.in, err, test.err)
+		}
+		if !p.IsZero() {
+			t.Errorf("DecodeInto(%s) left %x in dst", test.in, p)
+		}
+	}
+}
+
+func TestDecodeJSON32(t *testing.T) {
+	b58 := testVector32[5].b58
+	for _, in := range []string{
+		`"` + b58 + `"`,
+		` "` + b58 + `"` + "\n",
+		`"\u00` + hex.EncodeToString([]byte(b58[:1])) + b58[1:] + `"`,
+	} {
+		var out [32]byte
+		if err := DecodeJSON32(json.RawMessage(in), &out); err != nil {
+			t.Errorf("DecodeJSON32(%s) error = %v", in, err)
+		} else if hex.EncodeToString(out[:]) != testVector32[5].hex {
+			t.Errorf("DecodeJSON32(%s) = %x, want %s", in, out, testVector32[5].hex)
+		}
+	}
+
+	for _, test := range []struct {
+		in  string
+		err error
+	}{
+		{b58, ErrNotJSONString},
+		{`null`, ErrNotJSONString},
+		{`["` + b58 + `"]`, ErrNotJSONString},
+		{`"` + b58, nil},
+		{`"1111"`, ErrWrongLength},
+		{`"0` + b58[1:] + `"`, ErrEncode},
+	} {
+		var out [32]byte
+		err := DecodeJSON32(json.RawMessage(test.in), &out)
+		if err == nil || (test.err != nil && !errors.Is(err, test.err)) {
+			t.Errorf("DecodeJSON32(%s) error = %v, want %v", test.in, err, test.err)
+		}
+	}
+}
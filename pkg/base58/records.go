@@ -0,0 +1,64 @@
+package base58
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DecodeFixedRecords32 reads consecutive 44-byte records from r, decodes
+// each as a 32-byte value and passes it to fn, until r is exhausted.
+//
+// Keys with shorter encodings must be padded with trailing NULs, as
+// accepted by Decode32Padded.  A partial record at the end of the
+// stream is an error, as is any error returned by fn, which stops
+// decoding.
+func DecodeFixedRecords32(r io.Reader, fn func(key [32]byte) error) error {
+	var record [44]byte
+	for i := 0; ; i++ {
+		if _, err := io.ReadFull(r, record[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return fmt.Errorf("record %d: truncated", i)
+			}
+			return err
+		}
+		var key [32]byte
+		if !Decode32Padded(&key, record[:]) {
+			return decodeFailed(fmt.Errorf("record %d: %w", i, ErrEncode))
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+}
+
+// Decode32Prefixed reads a length byte followed by that many base58
+// characters from r and decodes them into out.
+//
+// A length outside [32, 44] is a *LengthError, and the characters are
+// not consumed.  io.EOF is returned if r is exhausted before the
+// length byte, and io.ErrUnexpectedEOF if it ends within the
+// characters.
+func Decode32Prefixed(r io.Reader, out *[32]byte) error {
+	var buf [44]byte
+	if _, err := io.ReadFull(r, buf[:1]); err != nil {
+		return err
+	}
+	n := int(buf[0])
+	if err := inputLenError32(n); err != nil {
+		return decodeFailed(err)
+	}
+	if _, err := io.ReadFull(r, buf[:n]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	if err := decode32Err(out, buf[:n]); err != nil {
+		return decodeFailed(err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package base58
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecodeFixedRecords32(t *testing.T) {
+	var stream strings.Builder
+	var want []string
+	for _, test := range testVector32 {
+		var record [44]byte
+		copy(record[:], test.b58)
+		stream.Write(record[:])
+		want = append(want, test.hex)
+	}
+
+	var got []string
+	collect := func(key [32]byte) error {
+		got = append(got, hex.EncodeToString(key[:]))
+		return nil
+	}
+	if err := DecodeFixedRecords32(strings.NewReader(stream.String()), collect); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("decoded %v, want %v", got, want)
+	}
+
+	got = nil
+	truncated := stream.String() + testVector32[5].b58[:20]
+	err := DecodeFixedRecords32(strings.NewReader(truncated), collect)
+	if err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("DecodeFixedRecords32(truncated) error = %v", err)
+	}
+	if len(got) != len(want) {
+		t.Errorf("decoded %d records before the truncated tail, want %d", len(got), len(want))
+	}
+}
+
+func TestDecode32Prefixed(t *testing.T) {
+	var stream []byte
+	for _, test := range testVector32 {
+		stream = append(stream, byte(len(test.b58)))
+		stream = append(stream, test.b58...)
+	}
+	r := bytes.NewReader(stream)
+	for _, test := range testVector32 {
+		var out [32]byte
+		if err := Decode32Prefixed(r, &out); err != nil || hex.EncodeToString(out[:]) != test.hex {
+			t.Errorf("Decode32Prefixed() = %x, %v, want %s", out, err, test.hex)
+		}
+	}
+	var out [32]byte
+	if err := Decode32Prefixed(r, &out); err != io.EOF {
+		t.Errorf("Decode32Prefixed(end) error = %v, want io.EOF", err)
+	}
+
+	for _, test := range []struct {
+		in  []byte
+		err error
+	}{
+		{append([]byte{31}, testVector32[5].b58[:31]...), ErrWrongLength},
+		{append([]byte{45}, testVector32[5].b58+"1"...), ErrWrongLength},
+		{[]byte{0}, ErrWrongLength},
+		{append([]byte{44}, testVector32[5].b58[:20]...), io.ErrUnexpectedEOF},
+		{append([]byte{44}, "0"+testVector32[5].b58[1:]...), ErrEncode},
+	} {
+		if err := Decode32Prefixed(bytes.NewReader(test.in), &out); !errors.Is(err, test.err) {
+			t.Errorf("Decode32Prefixed(%q) error = %v, want %v", test.in, err, test.err)
+		}
+	}
+}
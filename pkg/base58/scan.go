@@ -0,0 +1,19 @@
+package base58
+
+import (
+	"bufio"
+	"fmt"
+)
+
+// ScanBase58 is a bufio.SplitFunc that returns whitespace-separated
+// tokens, failing on a token whose length is outside the 32 to 88
+// character range of encoded 32 and 64 byte values.
+//
+// Only the length is checked; tokens still need to be decoded.
+func ScanBase58(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	advance, token, err = bufio.ScanWords(data, atEOF)
+	if err == nil && token != nil && (len(token) < 32 || len(token) > 88) {
+		return 0, nil, fmt.Errorf("%w: token of %d characters", ErrWrongLength, len(token))
+	}
+	return advance, token, err
+}
@@ -0,0 +1,34 @@
+package base58
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestScanBase58(t *testing.T) {
+	input := testVector32[5].b58 + "\n" +
+		"  " + testVector64[5].b58 + "\t" + testVector32[0].b58 + "\r\n\n"
+	s := bufio.NewScanner(strings.NewReader(input))
+	s.Split(ScanBase58)
+	var got []string
+	for s.Scan() {
+		got = append(got, s.Text())
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{testVector32[5].b58, testVector64[5].b58, testVector32[0].b58}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("scanned %q, want %q", got, want)
+	}
+
+	s = bufio.NewScanner(strings.NewReader(testVector32[5].b58 + "\nshort\n"))
+	s.Split(ScanBase58)
+	for s.Scan() {
+	}
+	if !errors.Is(s.Err(), ErrWrongLength) {
+		t.Errorf("Err() = %v, want ErrWrongLength", s.Err())
+	}
+}
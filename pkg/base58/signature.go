@@ -0,0 +1,45 @@
+package base58
+
+import "fmt"
+
+// Signature is a 64-byte ed25519 signature.
+type Signature [64]byte
+
+// String returns the base58 encoding of the signature.
+func (s Signature) String() string {
+	return Encode(s[:])
+}
+
+// Bytes returns the signature as a byte slice.
+func (s Signature) Bytes() []byte {
+	return s[:]
+}
+
+// Value is a decoded base58 value of either size, a Pubkey or a Signature.
+type Value interface {
+	Bytes() []byte
+	String() string
+}
+
+// ParseAny decodes s as a Pubkey or a Signature, depending on its length.
+//
+// The encoded lengths of the two sizes do not overlap, so the
+// interpretation is unambiguous.
+func ParseAny(s string) (Value, error) {
+	switch {
+	case checkInputLen(len(s), 32, 44) == nil:
+		p, err := parsePubkey(s)
+		if err != nil {
+			return nil, decodeFailed(err)
+		}
+		return p, nil
+	case checkInputLen(len(s), 64, 88) == nil:
+		var sig Signature
+		if !Decode64((*[64]byte)(&sig), []byte(s)) {
+			return nil, decodeFailed(ErrEncode)
+		}
+		return sig, nil
+	default:
+		return nil, decodeFailed(fmt.Errorf("%w: %d characters is neither a pubkey nor a signature", ErrWrongLength, len(s)))
+	}
+}
@@ -0,0 +1,38 @@
+package base58
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseAny(t *testing.T) {
+	for _, test := range testVector32 {
+		v, err := ParseAny(test.b58)
+		if err != nil {
+			t.Errorf("ParseAny(%s) error = %v", test.b58, err)
+			continue
+		}
+		if _, ok := v.(Pubkey); !ok || v.String() != test.b58 {
+			t.Errorf("ParseAny(%s) = %T %s", test.b58, v, v)
+		}
+	}
+	for _, test := range testVector64 {
+		v, err := ParseAny(test.b58)
+		if err != nil {
+			t.Errorf("ParseAny(%s) error = %v", test.b58, err)
+			continue
+		}
+		if _, ok := v.(Signature); !ok || v.String() != test.b58 {
+			t.Errorf("ParseAny(%s) = %T %s", test.b58, v, v)
+		}
+	}
+
+	for _, s := range []string{"", "1111", testVector32[3].b58 + "1"} {
+		if _, err := ParseAny(s); !errors.Is(err, ErrWrongLength) {
+			t.Errorf("ParseAny(%q) error = %v, want ErrWrongLength", s, err)
+		}
+	}
+	if _, err := ParseAny("0" + testVector64[1].b58[1:]); err != ErrEncode {
+		t.Errorf("ParseAny() error = %v, want ErrEncode", err)
+	}
+}
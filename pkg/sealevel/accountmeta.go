@@ -0,0 +1,29 @@
+package sealevel
+
+import "go.firedancer.io/radiance/pkg/base58"
+
+// AccountMeta describes how an invocation uses an account.
+type AccountMeta struct {
+	Pubkey   string `json:"pubkey"` // base58
+	Signer   bool   `json:"signer"`
+	Writable bool   `json:"writable"`
+}
+
+// AccountMetas returns the account metas of the params in order,
+// with duplicates resolved to the account they refer to.
+func (p *Params) AccountMetas() []AccountMeta {
+	metas := make([]AccountMeta, len(p.Accounts))
+	for i := range p.Accounts {
+		acc := &p.Accounts[i]
+		canonical := acc
+		if resolved, err := p.account(i); err == nil {
+			canonical = resolved
+		}
+		metas[i] = AccountMeta{
+			Pubkey:   base58.Encode(canonical.Key[:]),
+			Signer:   acc.IsSigner || canonical.IsSigner,
+			Writable: acc.IsWritable || canonical.IsWritable,
+		}
+	}
+	return metas
+}
@@ -0,0 +1,54 @@
+package sealevel
+
+import (
+	"bytes"
+	"time"
+
+	"go.firedancer.io/radiance/pkg/base58"
+)
+
+// AuditSink receives an AuditEntry for every successful Update of params
+// whose Serializer has Audit set.
+//
+// Record is called synchronously from Update and must not retain the
+// params.
+type AuditSink interface {
+	Record(AuditEntry)
+}
+
+// AuditEntry describes the account changes applied by one Update call.
+type AuditEntry struct {
+	Time      time.Time
+	ProgramID string // base58
+	Accounts  []AuditAccount
+}
+
+// AuditAccount is an account whose owner, lamports, or data were
+// modified by an update.
+type AuditAccount struct {
+	Index         int
+	Key           string // base58
+	LamportsDelta int64  // saturated, as by Params.LamportsDeltas
+}
+
+// auditAccount returns the audit record of the account at index i, or
+// false if the update left it unmodified.
+func auditAccount(i int, prev, acc *AccountParam) (AuditAccount, bool) {
+	if acc.Owner == prev.Owner && acc.Lamports == prev.Lamports && dataUnchanged(prev, acc) {
+		return AuditAccount{}, false
+	}
+	return AuditAccount{
+		Index:         i,
+		Key:           base58.Pubkey(acc.Key).String(),
+		LamportsDelta: lamportsDelta(prev.Lamports, acc.Lamports),
+	}, true
+}
+
+// dataUnchanged reports whether the data of acc matches that of prev.
+// Streamed data is not kept around and always counts as modified.
+func dataUnchanged(prev, acc *AccountParam) bool {
+	if prev.Data == nil && prev.DataSource != nil {
+		return false
+	}
+	return bytes.Equal(prev.Data, acc.Data)
+}
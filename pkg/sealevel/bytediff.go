@@ -0,0 +1,73 @@
+package sealevel
+
+import "bytes"
+
+// ByteRange is the half-open range [Start, End) of byte offsets within
+// account data.
+type ByteRange struct {
+	Start, End int
+}
+
+// UpdateWithByteDiff is like Update, but also reports which bytes of
+// each account's data changed, keyed by account index. Accounts whose
+// data is unchanged, duplicates, and accounts whose data was streamed
+// from a DataSource are omitted.
+//
+// Bytes past the end of the shorter of the old and new data count as
+// changed, so growing or shrinking an account reports the tail.
+func (p *Params) UpdateWithByteDiff(buf *bytes.Reader) (map[int][]ByteRange, error) {
+	before := make([][]byte, len(p.Accounts))
+	for i := range p.Accounts {
+		acc := &p.Accounts[i]
+		if acc.IsDuplicate || (acc.DataSource != nil && acc.Data == nil) {
+			continue
+		}
+		// Never nil, so that empty data still gets diffed.
+		before[i] = append([]byte{}, acc.Data...)
+	}
+	if err := p.Update(buf); err != nil {
+		return nil, err
+	}
+
+	diffs := make(map[int][]ByteRange)
+	for i, old := range before {
+		if old == nil {
+			continue
+		}
+		if ranges := byteDiff(old, p.Accounts[i].Data); len(ranges) > 0 {
+			diffs[i] = ranges
+		}
+	}
+	return diffs, nil
+}
+
+// byteDiff returns the ranges of offsets at which a and b differ.
+func byteDiff(a, b []byte) []ByteRange {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	var ranges []ByteRange
+	for i := 0; i < n; i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		start := i
+		for i < n && a[i] != b[i] {
+			i++
+		}
+		ranges = append(ranges, ByteRange{start, i})
+	}
+	if len(a) != len(b) {
+		end := len(a)
+		if len(b) > end {
+			end = len(b)
+		}
+		if k := len(ranges) - 1; k >= 0 && ranges[k].End == n {
+			ranges[k].End = end
+		} else {
+			ranges = append(ranges, ByteRange{n, end})
+		}
+	}
+	return ranges
+}
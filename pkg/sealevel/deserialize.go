@@ -0,0 +1,332 @@
+package sealevel
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Deserialize parses an input region in the default layout, as
+// produced by Serialize.
+func Deserialize(buf []byte) (*Params, error) {
+	p := new(Params)
+	if err := DeserializeInto(p, buf); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// DeserializeAllowTrailing is like Deserialize, but ignores any bytes
+// following the input region, as when buf is a window into a larger
+// mapping. It returns the number of bytes the input region occupies.
+func DeserializeAllowTrailing(buf []byte) (*Params, int, error) {
+	p := new(Params)
+	n, err := p.deserialize(buf, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	return p, n, nil
+}
+
+// DeserializeArena is like Deserialize, but copies the data of all
+// accounts into a single buffer allocated up front, which it returns
+// as the arena. The Data of each account is a sub-slice of the arena,
+// with its capacity capped at its length such that appending to it
+// does not overwrite other accounts. Duplicate accounts share the
+// sub-slice of the account they refer to.
+//
+// The caller controls the lifetime of the arena, for example to return
+// it to a pool once the params are no longer used.
+func DeserializeArena(buf []byte) (*Params, []byte, error) {
+	arena := make([]byte, 0, accountDataSize(buf))
+	p := new(Params)
+	n, err := p.deserialize(buf, &arena)
+	if err != nil {
+		return nil, nil, err
+	}
+	if n != len(buf) {
+		return nil, nil, fmt.Errorf("%d trailing bytes after program ID", len(buf)-n)
+	}
+	return p, arena, nil
+}
+
+// accountDataSize returns the total data length of the accounts in the
+// input region at the start of buf, or 0 if the accounts are malformed.
+func accountDataSize(buf []byte) int {
+	d := &decoder{buf: buf}
+	count := d.u64()
+	if count > uint64(len(buf)/8) {
+		return 0
+	}
+	var total int
+	for i := uint64(0); i < count && d.err == nil; i++ {
+		if d.u8() != 0xFF {
+			d.skip(7)
+			continue
+		}
+		d.skip(7 + 32 + 32 + 8)
+		n := d.u64()
+		if n > MaxPermittedDataLength {
+			return 0
+		}
+		d.skip(int(n))
+		d.skip(reallocPadding(d.off))
+		d.skip(8)
+		total += int(n)
+	}
+	if d.err != nil {
+		return 0
+	}
+	return total
+}
+
+// DeserializeInto parses an input region into p, in the layout selected
+// by p.Serializer. An execution context is expected only if p.Context
+// is non-nil.
+//
+// The accounts slice, account data buffers, and instruction data buffer
+// already held by p are reused where their capacity suffices, and
+// overwritten in the process. Data is copied out of buf, so buf may be
+// reused once DeserializeInto returns. Duplicate accounts carry the key
+// of the account they refer to, but no flags or data.
+func DeserializeInto(p *Params, buf []byte) error {
+	n, err := p.deserialize(buf, nil)
+	if err != nil {
+		return err
+	}
+	if n != len(buf) {
+		return fmt.Errorf("%d trailing bytes after program ID", len(buf)-n)
+	}
+	return nil
+}
+
+// ReadFrom reads an input region from r until EOF and parses it into p
+// as DeserializeInto does, implementing io.ReaderFrom.
+func (p *Params) ReadFrom(r io.Reader) (int64, error) {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return int64(len(buf)), err
+	}
+	return int64(len(buf)), DeserializeInto(p, buf)
+}
+
+// deserialize parses the input region at the start of buf and returns
+// the number of bytes consumed. If arena is non-nil, account data is
+// appended to it instead of the data buffers held by p.
+func (p *Params) deserialize(buf []byte, arena *[]byte) (int, error) {
+	d := &decoder{buf: buf}
+	opts := p.serializer()
+	p.index = nil
+
+	count := d.u64()
+	// Every account takes at least 8 bytes.
+	if d.err != nil || count > uint64(len(buf)/8) {
+		return 0, fmt.Errorf("invalid account count %d", count)
+	}
+	if cap(p.Accounts) >= int(count) {
+		p.Accounts = p.Accounts[:count]
+	} else {
+		p.Accounts = append(p.Accounts[:cap(p.Accounts)], make([]AccountParam, int(count)-cap(p.Accounts))...)
+	}
+
+	for i := range p.Accounts {
+		acc := &p.Accounts[i]
+		*acc = AccountParam{Data: acc.Data[:0]}
+
+		idx := d.u8()
+		if idx != 0xFF {
+			if int(idx) >= i {
+				return 0, fmt.Errorf("account %d: duplicate index %d does not refer to an earlier account", i, idx)
+			}
+			acc.IsDuplicate = true
+			acc.DuplicateIndex = idx
+			acc.Key = p.Accounts[idx].Key
+			if arena != nil {
+				acc.Data = p.Accounts[idx].Data
+			}
+			d.skip(7)
+			continue
+		}
+
+		if err := readAccountFlags(d, acc, opts.FlagsLayout); err != nil {
+			return 0, fmt.Errorf("account %d: %w", i, err)
+		}
+		copy(acc.Key[:], d.bytes(32))
+		copy(acc.Owner[:], d.bytes(32))
+		acc.Lamports = d.u64()
+
+		dataLen := d.u64()
+		if dataLen > MaxPermittedDataLength {
+			return 0, fmt.Errorf("account %d: data length %d exceeds maximum of %d", i, dataLen, MaxPermittedDataLength)
+		}
+		if arena != nil {
+			start := len(*arena)
+			*arena = append(*arena, d.bytes(int(dataLen))...)
+			acc.Data = (*arena)[start:len(*arena):len(*arena)]
+		} else {
+			acc.Data = append(acc.Data, d.bytes(int(dataLen))...)
+		}
+		acc.originalDataLen = int(dataLen)
+		acc.txStartDataLen = int(dataLen)
+		acc.txStarted = true
+		// The serializer pads with zeros, so non-zero bytes mean the
+		// padding in buf differs from what Update expects, and the rent
+		// epoch would be read from the wrong place.
+		acc.Padding = reallocPadding(d.off)
+		if pad := d.bytes(acc.Padding); pad != nil && !isZero(pad) {
+			return 0, fmt.Errorf("account %d: realloc padding does not match expected %d zero bytes", i, acc.Padding)
+		}
+		acc.RentEpoch = d.u64()
+		if d.err != nil {
+			return 0, fmt.Errorf("account %d: %w", i, d.err)
+		}
+		// Zero padding followed by a zero rent epoch passes the check
+		// above even when the padding is too long, so also check that
+		// what follows the rent epoch is where the layout expects it.
+		if !validBoundary(d.buf[d.off:], i, i == len(p.Accounts)-1, opts.InstructionContext) {
+			return 0, fmt.Errorf("account %d: realloc padding is not the expected %d bytes: rent epoch is not followed by the next field", i, acc.Padding)
+		}
+	}
+
+	if opts.InstructionContext {
+		p.InstructionIndex = d.u8()
+		p.StackHeight = d.u8()
+		d.skip(6)
+	}
+	dataLen := d.u64()
+	if dataLen > uint64(len(buf)) {
+		return 0, fmt.Errorf("instruction data length %d exceeds input", dataLen)
+	}
+	p.Data = append(p.Data[:0], d.bytes(int(dataLen))...)
+	copy(p.ProgramID[:], d.bytes(32))
+	if p.Context != nil {
+		_ = p.Context.readFrom(d)
+	}
+	if d.err != nil {
+		return 0, fmt.Errorf("instruction: %w", d.err)
+	}
+	return d.off, nil
+}
+
+// validBoundary reports whether b, the input following the rent epoch
+// of account i, starts with the header of account i+1, or with the
+// instruction trailer if last is set.
+func validBoundary(b []byte, i int, last, instructionContext bool) bool {
+	if last {
+		if instructionContext {
+			if len(b) < 8 || !isZero(b[2:8]) {
+				return false
+			}
+			b = b[8:]
+		}
+		if len(b) < 8+32 {
+			return false
+		}
+		dataLen := binary.LittleEndian.Uint64(b)
+		return dataLen <= uint64(len(b)-8-32)
+	}
+	if len(b) < 8 {
+		return false
+	}
+	if b[0] != 0xFF {
+		// A duplicate may refer to any account up to and including i.
+		return int(b[0]) <= i && isZero(b[1:8])
+	}
+	return b[1] <= 1 && b[2] <= 1 && b[3] <= 1 && isZero(b[4:8])
+}
+
+// decoder reads little-endian values from a buffer and remembers
+// whether it ran out of input, after which all reads return zeros.
+type decoder struct {
+	buf []byte
+	off int
+	err error
+}
+
+func (d *decoder) bytes(n int) []byte {
+	if d.err != nil || n > len(d.buf)-d.off {
+		d.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := d.buf[d.off : d.off+n]
+	d.off += n
+	return b
+}
+
+func (d *decoder) Read(b []byte) (int, error) {
+	copy(b, d.bytes(len(b)))
+	if d.err != nil {
+		return 0, d.err
+	}
+	return len(b), nil
+}
+
+func (d *decoder) skip(n int) {
+	d.bytes(n)
+}
+
+func (d *decoder) u8() uint8 {
+	if b := d.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) u64() uint64 {
+	if b := d.bytes(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+// DefaultPoolDataCap is the default capacity above which ParamsPool
+// drops data buffers instead of retaining them.
+const DefaultPoolDataCap = 64 * 1024
+
+// ParamsPool recycles Params for use with DeserializeInto.
+//
+// Put retains the accounts slice and the data buffers of a Params, so
+// the caller must not use the Params or any slice obtained from it,
+// including account data, after handing it back. Params returned by
+// Get are empty and use the default layout.
+//
+// A ParamsPool is safe for concurrent use and must not be copied after
+// first use.
+type ParamsPool struct {
+	// MaxDataCap is the capacity above which data buffers are dropped
+	// rather than retained. Zero means DefaultPoolDataCap.
+	MaxDataCap int
+
+	pool sync.Pool
+}
+
+// Get returns a Params from the pool, or a new one.
+func (pp *ParamsPool) Get() *Params {
+	if p, ok := pp.pool.Get().(*Params); ok {
+		return p
+	}
+	return new(Params)
+}
+
+// Put returns p to the pool.
+func (pp *ParamsPool) Put(p *Params) {
+	maxCap := pp.MaxDataCap
+	if maxCap == 0 {
+		maxCap = DefaultPoolDataCap
+	}
+	for i := range p.Accounts {
+		acc := &p.Accounts[i]
+		data := acc.Data
+		if cap(data) > maxCap {
+			data = nil
+		}
+		*acc = AccountParam{Data: data[:0]}
+	}
+	data := p.Data
+	if cap(data) > maxCap {
+		data = nil
+	}
+	*p = Params{Accounts: p.Accounts[:0], Data: data[:0]}
+	pp.pool.Put(p)
+}
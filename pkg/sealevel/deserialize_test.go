@@ -0,0 +1,186 @@
+package sealevel
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeserialize_Roundtrip(t *testing.T) {
+	params := testParams()
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+
+	got, err := Deserialize(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, params.Accounts[0].Data, got.Accounts[0].Data)
+	assert.Equal(t, params.Accounts[1].RentEpoch, got.Accounts[1].RentEpoch)
+	assert.True(t, got.Accounts[2].IsDuplicate)
+	assert.Equal(t, params.Accounts[0].Key, got.Accounts[2].Key)
+	assert.Equal(t, params.Data, got.Data)
+	assert.Equal(t, params.ProgramID, got.ProgramID)
+
+	var again bytes.Buffer
+	require.NoError(t, got.Serialize(&again))
+	assert.Equal(t, buf.Bytes(), again.Bytes())
+
+	// The deserialized params can read back program output.
+	require.NoError(t, got.Update(bytes.NewReader(buf.Bytes())))
+}
+
+func TestDeserialize_Invalid(t *testing.T) {
+	params := testParams()
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	out := buf.Bytes()
+
+	_, err := Deserialize(out[:len(out)-1])
+	assert.Error(t, err)
+	_, err = Deserialize(append(out, 0))
+	assert.Error(t, err)
+	_, err = Deserialize([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF})
+	assert.Error(t, err)
+}
+
+func TestDeserialize_WrongPadding(t *testing.T) {
+	params := testParams()
+	params.Accounts[0].RentEpoch = 7
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	out := buf.Bytes()
+	padStart := params.DataOffsets()[0] + len(params.Accounts[0].Data)
+
+	// Eight bytes short, so the rent epoch lands in the expected padding.
+	short := append(append([]byte(nil), out[:padStart]...), out[padStart+8:]...)
+	_, err := Deserialize(short)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "account 0: realloc padding")
+
+	// Eight bytes too many.
+	long := append(append(append([]byte(nil), out[:padStart]...), make([]byte, 8)...), out[padStart:]...)
+	_, err = Deserialize(long)
+	assert.Error(t, err)
+}
+
+func TestDeserialize_WrongPaddingZeroRentEpoch(t *testing.T) {
+	// With a zero rent epoch, the misplaced bytes are all zero and pass
+	// the padding check, so the error must come from the position of
+	// the rent epoch instead.
+	params := testParams()
+	params.Accounts[0].RentEpoch = 0
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	out := buf.Bytes()
+	padStart := params.DataOffsets()[0] + len(params.Accounts[0].Data)
+
+	short := append(append([]byte(nil), out[:padStart]...), out[padStart+8:]...)
+	_, err := Deserialize(short)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "account 0: realloc padding")
+
+	// Eight bytes too many leaves the zero rent epoch where the next
+	// header is expected, and it reads as a duplicate of account 0, so
+	// only the length of the input shows the mismatch.
+	long := append(append(append([]byte(nil), out[:padStart]...), make([]byte, 8)...), out[padStart:]...)
+	_, err = Deserialize(long)
+	assert.Error(t, err)
+}
+
+func TestDeserializeAllowTrailing(t *testing.T) {
+	params := testParams()
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	out := buf.Bytes()
+
+	got, n, err := DeserializeAllowTrailing(out)
+	require.NoError(t, err)
+	assert.Equal(t, len(out), n)
+	assert.Equal(t, params.ProgramID, got.ProgramID)
+
+	long := append(append([]byte(nil), out...), 0xAA, 0xBB, 0xCC)
+	got, n, err = DeserializeAllowTrailing(long)
+	require.NoError(t, err)
+	assert.Equal(t, len(out), n)
+	assert.Equal(t, params.Data, got.Data)
+	assert.Equal(t, params.ProgramID, got.ProgramID)
+
+	_, _, err = DeserializeAllowTrailing(out[:len(out)-1])
+	assert.Error(t, err)
+}
+
+func TestDeserializeArena(t *testing.T) {
+	params := testParams()
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+
+	got, arena, err := DeserializeArena(buf.Bytes())
+	require.NoError(t, err)
+	assert.Len(t, arena, len(params.Accounts[0].Data)+len(params.Accounts[1].Data))
+	assert.Equal(t, cap(arena), len(arena))
+
+	// Every data slice, including that of the duplicate, lies within
+	// the arena.
+	inArena := func(b []byte) bool {
+		if len(b) == 0 {
+			return true
+		}
+		start := uintptr(unsafe.Pointer(&arena[0]))
+		p := uintptr(unsafe.Pointer(&b[0]))
+		return p >= start && p+uintptr(len(b)) <= start+uintptr(len(arena))
+	}
+	for i, acc := range got.Accounts {
+		assert.True(t, inArena(acc.Data), "account %d data outside arena", i)
+		assert.Equal(t, len(acc.Data), cap(acc.Data), "account %d", i)
+	}
+	assert.Equal(t, params.Accounts[0].Data, got.Accounts[0].Data)
+	assert.Equal(t, params.Accounts[1].Data, got.Accounts[1].Data)
+	assert.Same(t, &got.Accounts[0].Data[0], &got.Accounts[2].Data[0])
+
+	_, _, err = DeserializeArena(buf.Bytes()[:buf.Len()-1])
+	assert.Error(t, err)
+}
+
+func TestDeserializeInto_Reuse(t *testing.T) {
+	params := testParams()
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+
+	var p Params
+	require.NoError(t, DeserializeInto(&p, buf.Bytes()))
+	data := p.Accounts[1].Data
+	require.NoError(t, DeserializeInto(&p, buf.Bytes()))
+	assert.Same(t, &data[0], &p.Accounts[1].Data[0])
+}
+
+func TestParamsPool_Concurrent(t *testing.T) {
+	params := testParams()
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	in := buf.Bytes()
+
+	var pool ParamsPool
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				p := pool.Get()
+				if err := DeserializeInto(p, in); err != nil {
+					t.Error(err)
+					return
+				}
+				if !bytes.Equal(p.Accounts[0].Data, params.Accounts[0].Data) {
+					t.Error("data mismatch")
+				}
+				p.Accounts[0].Data[0]++
+				pool.Put(p)
+			}
+		}()
+	}
+	wg.Wait()
+}
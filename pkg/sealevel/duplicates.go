@@ -0,0 +1,89 @@
+package sealevel
+
+import (
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// MarkDuplicates derives the duplicate markers of accounts from their keys.
+//
+// Every account whose key matches an earlier account is marked as a
+// duplicate of the first account with that key, and every first
+// occurrence is marked as a full account. All entries, including ones
+// previously marked as duplicates, must carry their key.
+//
+// Duplicate indices are limited to 8 bits, so an error is returned if
+// an account refers to a first occurrence at index 255 or later.
+func MarkDuplicates(accounts []AccountParam) error {
+	first := make(map[solana.PublicKey]int, len(accounts))
+	for i := range accounts {
+		acc := &accounts[i]
+		idx, ok := first[acc.Key]
+		if !ok {
+			first[acc.Key] = i
+			acc.IsDuplicate = false
+			acc.DuplicateIndex = 0
+			continue
+		}
+		if idx >= 0xFF {
+			return fmt.Errorf("account %d: duplicate of account %d cannot be referenced", i, idx)
+		}
+		acc.IsDuplicate = true
+		acc.DuplicateIndex = uint8(idx)
+	}
+	return nil
+}
+
+// Canonicalize rewrites the accounts into minimal form: the first
+// occurrence of each key becomes a full entry and every later
+// occurrence a duplicate marker referring to it, whether it was
+// previously a full entry or a duplicate of another index.
+//
+// The privileges of all occurrences of a key are merged into its first
+// occurrence. An error is returned if a duplicate would need to refer
+// to an index beyond the 8-bit range, in which case p is unchanged.
+func (p *Params) Canonicalize() error {
+	accounts := make([]AccountParam, len(p.Accounts))
+	for i := range p.Accounts {
+		acc, err := p.account(i)
+		if err != nil {
+			return err
+		}
+		accounts[i] = *acc
+		accounts[i].IsSigner = acc.IsSigner || p.Accounts[i].IsSigner
+		accounts[i].IsWritable = acc.IsWritable || p.Accounts[i].IsWritable
+	}
+	if err := MarkDuplicates(accounts); err != nil {
+		return err
+	}
+	for i := range accounts {
+		acc := &accounts[i]
+		if !acc.IsDuplicate {
+			continue
+		}
+		orig := &accounts[acc.DuplicateIndex]
+		orig.IsSigner = orig.IsSigner || acc.IsSigner
+		orig.IsWritable = orig.IsWritable || acc.IsWritable
+		*acc = AccountParam{
+			IsDuplicate:    true,
+			DuplicateIndex: acc.DuplicateIndex,
+			IsSigner:       acc.IsSigner,
+			IsWritable:     acc.IsWritable,
+			Key:            acc.Key,
+		}
+	}
+	p.Accounts = accounts
+	p.index = nil
+	return nil
+}
+
+// CanonicalAccounts calls fn with each account that is not a duplicate
+// marker, in order, along with its index in p.Accounts.
+func (p *Params) CanonicalAccounts(fn func(index int, acc *AccountParam)) {
+	for i := range p.Accounts {
+		if !p.Accounts[i].IsDuplicate {
+			fn(i, &p.Accounts[i])
+		}
+	}
+}
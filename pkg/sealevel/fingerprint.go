@@ -0,0 +1,95 @@
+package sealevel
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+)
+
+// AccountsFingerprint returns a SHA-256 digest of the account identities
+// and flags of the params.
+//
+// Data, lamports, and rent epochs are not covered, so the fingerprint
+// only changes when the account set does. Duplicates contribute their
+// duplicate index, not the fields of the account they refer to.
+func (p *Params) AccountsFingerprint() [32]byte {
+	h := sha256.New()
+	var n [8]byte
+	binary.LittleEndian.PutUint64(n[:], uint64(len(p.Accounts)))
+	h.Write(n[:])
+	for i := range p.Accounts {
+		acc := &p.Accounts[i]
+		if acc.IsDuplicate {
+			h.Write([]byte{acc.DuplicateIndex})
+			continue
+		}
+		h.Write([]byte{0xFF})
+		_ = writeAccountFlags(h, acc, FlagsUnpacked)
+		h.Write(acc.Key[:])
+		h.Write(acc.Owner[:])
+	}
+	var out [32]byte
+	h.Sum(out[:0])
+	return out
+}
+
+// CanonicalHash returns a SHA-256 digest of the params with accounts
+// sorted by key, so that params holding the same accounts in a
+// different order hash identically.
+//
+// Each account slot, duplicates included, contributes the flags, key,
+// owner, lamports, data, and rent epoch of the account it resolves to,
+// followed by the instruction data and program ID. Data supplied
+// through a DataSource is not covered.
+//
+// The hash is meant for caching and deduplication only. The VM must
+// be given the accounts in their original order, as serialized by
+// Serialize.
+func (p *Params) CanonicalHash() [32]byte {
+	order := make([]int, len(p.Accounts))
+	for i := range order {
+		order[i] = i
+	}
+	key := func(i int) []byte {
+		if acc, err := p.account(i); err == nil {
+			return acc.Key[:]
+		}
+		return nil
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return bytes.Compare(key(order[a]), key(order[b])) < 0
+	})
+
+	h := sha256.New()
+	var n [8]byte
+	binary.LittleEndian.PutUint64(n[:], uint64(len(p.Accounts)))
+	h.Write(n[:])
+	for _, i := range order {
+		acc, err := p.account(i)
+		if err != nil {
+			// Unresolvable duplicates have no place in the sort order,
+			// but must still change the hash.
+			h.Write([]byte{p.Accounts[i].DuplicateIndex})
+			continue
+		}
+		h.Write([]byte{0xFF})
+		_ = writeAccountFlags(h, acc, FlagsUnpacked)
+		h.Write(acc.Key[:])
+		h.Write(acc.Owner[:])
+		binary.LittleEndian.PutUint64(n[:], acc.Lamports)
+		h.Write(n[:])
+		binary.LittleEndian.PutUint64(n[:], uint64(len(acc.Data)))
+		h.Write(n[:])
+		h.Write(acc.Data)
+		binary.LittleEndian.PutUint64(n[:], acc.RentEpoch)
+		h.Write(n[:])
+	}
+	binary.LittleEndian.PutUint64(n[:], uint64(len(p.Data)))
+	h.Write(n[:])
+	h.Write(p.Data)
+	h.Write(p.ProgramID[:])
+	var out [32]byte
+	h.Sum(out[:0])
+	return out
+}
@@ -0,0 +1,27 @@
+package sealevel
+
+import "unsafe"
+
+// MemoryFootprint estimates the heap bytes held by the params: the
+// accounts slice, account and instruction data capacities, and the
+// optional serializer and execution context.
+//
+// Data of duplicate accounts is assumed to alias the account they refer
+// to, and data streamed from a DataSource is not counted.
+func (p *Params) MemoryFootprint() int {
+	n := int(unsafe.Sizeof(*p))
+	n += cap(p.Accounts) * int(unsafe.Sizeof(AccountParam{}))
+	for i := range p.Accounts {
+		if acc := &p.Accounts[i]; !acc.IsDuplicate {
+			n += cap(acc.Data)
+		}
+	}
+	n += cap(p.Data)
+	if p.Serializer != nil {
+		n += int(unsafe.Sizeof(*p.Serializer))
+	}
+	if p.Context != nil {
+		n += int(unsafe.Sizeof(*p.Context))
+	}
+	return n
+}
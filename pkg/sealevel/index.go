@@ -0,0 +1,44 @@
+package sealevel
+
+import "github.com/gagliardetto/solana-go"
+
+// BuildIndex builds a map from the key of each full account entry to
+// its index, which AccountByKey uses for constant-time lookups.
+//
+// The index is dropped by Canonicalize and when p is deserialized into,
+// and ignored once the number of accounts changes. Callers that reorder
+// or rekey accounts in place must call BuildIndex again; AccountByKey
+// never returns a wrong account, but may miss a rekeyed one.
+func (p *Params) BuildIndex() {
+	p.index = make(map[solana.PublicKey]int, len(p.Accounts))
+	p.indexLen = len(p.Accounts)
+	for i := range p.Accounts {
+		acc := &p.Accounts[i]
+		if _, seen := p.index[acc.Key]; !acc.IsDuplicate && !seen {
+			p.index[acc.Key] = i
+		}
+	}
+}
+
+// AccountByKey returns the first full account entry with the given key
+// and its index, or ok set to false if there is none.
+//
+// Without a current index built by BuildIndex, the accounts are scanned.
+func (p *Params) AccountByKey(key solana.PublicKey) (index int, acc *AccountParam, ok bool) {
+	if p.index != nil && p.indexLen == len(p.Accounts) {
+		i, found := p.index[key]
+		if !found {
+			return -1, nil, false
+		}
+		if acc := &p.Accounts[i]; !acc.IsDuplicate && acc.Key == key {
+			return i, acc, true
+		}
+		// The accounts were reordered since BuildIndex.
+	}
+	for i := range p.Accounts {
+		if acc := &p.Accounts[i]; !acc.IsDuplicate && acc.Key == key {
+			return i, acc, true
+		}
+	}
+	return -1, nil, false
+}
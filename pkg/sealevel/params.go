@@ -5,8 +5,12 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
+	"time"
 
 	"github.com/gagliardetto/solana-go"
+	"go.firedancer.io/radiance/pkg/base58"
+	"go.firedancer.io/radiance/pkg/safemath"
 )
 
 // Params is the data passed to programs via the Sealevel VM input segment.
@@ -14,6 +18,136 @@ type Params struct {
 	Accounts  []AccountParam
 	Data      []byte // per-instruction data
 	ProgramID solana.PublicKey
+
+	// Instruction context, only serialized if enabled by the Serializer.
+	InstructionIndex uint8
+	StackHeight      uint8
+
+	// Context is appended after the program ID if non-nil.
+	Context *ExecutionContext
+
+	Serializer *Serializer // nil selects the defaults
+
+	index    map[solana.PublicKey]int // built by BuildIndex
+	indexLen int                      // len(Accounts) when index was built
+}
+
+// ExecutionContext describes the environment of an execution, for
+// runtimes that expose it to programs after the program ID.
+type ExecutionContext struct {
+	Slot     uint64
+	Epoch    uint64
+	FeePayer solana.PublicKey
+}
+
+// executionContextLen is the serialized size of an ExecutionContext.
+const executionContextLen = 8 + 8 + 32
+
+func (c *ExecutionContext) writeTo(w io.Writer) {
+	_ = binary.Write(w, binary.LittleEndian, c.Slot)
+	_ = binary.Write(w, binary.LittleEndian, c.Epoch)
+	_, _ = w.Write(c.FeePayer[:])
+}
+
+func (c *ExecutionContext) readFrom(r io.Reader) error {
+	var b [executionContextLen]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return err
+	}
+	c.Slot = binary.LittleEndian.Uint64(b[0:8])
+	c.Epoch = binary.LittleEndian.Uint64(b[8:16])
+	copy(c.FeePayer[:], b[16:])
+	return nil
+}
+
+// Serializer holds options controlling the layout of serialized params
+// and how they are read back by Update.
+// The zero value matches the layout of the current BPF loaders.
+type Serializer struct {
+	FlagsLayout FlagsLayout
+
+	// Strict enables additional consistency checks when reading back
+	// params.
+	Strict bool
+
+	// InstructionContext prefixes the instruction data with the
+	// instruction index and stack height, padded to 8 bytes.
+	InstructionContext bool
+
+	// DeploymentContext permits Update to accept changes to the data
+	// and executable flag of the account at index DeploymentAccount, as
+	// made by the loader when deploying a program. Otherwise, both are
+	// immutable for executable accounts.
+	DeploymentContext bool
+	DeploymentAccount int
+
+	// CumulativeRealloc bounds account growth across all instructions
+	// of a transaction: Update rejects data longer than the length at
+	// the start of the transaction plus ReallocSpace, in addition to
+	// the per-instruction limit. The transaction starts when an account
+	// is first serialized, or again after BeginTransaction.
+	CumulativeRealloc bool
+
+	// Audit, if set, receives an entry listing the modified accounts
+	// after every successful Update. ValidateUpdate does not record.
+	Audit AuditSink
+}
+
+var defaultSerializer Serializer
+
+func (p *Params) serializer() *Serializer {
+	if p.Serializer != nil {
+		return p.Serializer
+	}
+	return &defaultSerializer
+}
+
+// FlagsLayout selects how the signer, writable, and executable flags of
+// an account are encoded. Every layout occupies 7 bytes, such that the
+// rest of the account layout is unaffected.
+type FlagsLayout uint8
+
+const (
+	// FlagsUnpacked stores each flag in its own byte, followed by 4 bytes of padding.
+	FlagsUnpacked = FlagsLayout(iota)
+)
+
+// writeAccountFlags writes the flags of acc in the given layout.
+func writeAccountFlags(w io.Writer, acc *AccountParam, layout FlagsLayout) error {
+	switch layout {
+	case FlagsUnpacked:
+		var b [7]byte
+		if acc.IsSigner {
+			b[0] = 1
+		}
+		if acc.IsWritable {
+			b[1] = 1
+		}
+		if acc.IsExecutable {
+			b[2] = 1
+		}
+		_, err := w.Write(b[:])
+		return err
+	default:
+		return fmt.Errorf("unsupported flags layout %d", layout)
+	}
+}
+
+// readAccountFlags reads the flags of acc in the given layout.
+func readAccountFlags(r io.Reader, acc *AccountParam, layout FlagsLayout) error {
+	switch layout {
+	case FlagsUnpacked:
+		var b [7]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return err
+		}
+		acc.IsSigner = b[0] != 0
+		acc.IsWritable = b[1] != 0
+		acc.IsExecutable = b[2] != 0
+		return nil
+	default:
+		return fmt.Errorf("unsupported flags layout %d", layout)
+	}
 }
 
 // ReallocSpace is the allowed length by which an account is allowed to grow.
@@ -22,6 +156,18 @@ const ReallocSpace = 1024 * 10
 // ReallocAlign is the byte amount by which the data following a realloc is aligned.
 const ReallocAlign = 8
 
+// MaxPermittedDataLength is the maximum size of an account's data.
+const MaxPermittedDataLength = 10 * 1024 * 1024
+
+// MaxInputRegionSize is the maximum size of a serialized input region,
+// which the VM maps into a single 4 GiB memory region.
+const MaxInputRegionSize = 1 << 32
+
+// PacketDataLen is the maximum size of a serialized transaction sent
+// in a single packet. Signatures, account keys, and the rest of the
+// message share it with the instruction data.
+const PacketDataLen = 1232
+
 // AccountParam is an account input to a program execution.
 type AccountParam struct {
 	IsDuplicate    bool
@@ -35,108 +181,940 @@ type AccountParam struct {
 	Data           []byte
 	Padding        int // ignored, written by serializer
 	RentEpoch      uint64
+
+	// DataSource optionally provides the account data when Data is nil,
+	// allowing large accounts to be streamed into the serialized params.
+	// Data takes precedence whenever it is non-nil.
+	// Update always reads the account data back into Data.
+	DataSource io.ReaderAt
+
+	// DataLen is the length of the data in DataSource. If both Data and
+	// DataSource are nil, it acts as a placeholder for sizes and offsets
+	// computed before the data is loaded, and serializing fails.
+	DataLen int
+
+	// originalDataLen is the data length as last serialized.
+	// The program may shrink the account to any length and grow it
+	// back up to originalDataLen+ReallocSpace.
+	originalDataLen int
+
+	// txStartDataLen is the data length when the account was first
+	// serialized in the current transaction, if txStarted is set.
+	txStartDataLen int
+	txStarted      bool
 }
 
-// Serialize writes the params to the provided buffer.
-func (p *Params) Serialize(buf *bytes.Buffer) {
-	buf.Reset()
+// dataLen returns the length of the account data as serialized.
+func (acc *AccountParam) dataLen() int {
+	if acc.Data == nil {
+		return acc.DataLen
+	}
+	return len(acc.Data)
+}
 
-	_ = binary.Write(buf, binary.LittleEndian, uint64(len(p.Accounts)))
+// Validate checks the structural invariants of the params,
+// returning the first violation found.
+func (p *Params) Validate() error {
+	keys := make(map[solana.PublicKey]int, len(p.Accounts))
 	for i := range p.Accounts {
 		acc := &p.Accounts[i]
 
 		if acc.IsDuplicate {
-			_, _ = buf.Write([]byte{acc.DuplicateIndex})
-			_ = writeZeros(buf, 7)
+			idx := int(acc.DuplicateIndex)
+			if acc.DuplicateIndex == 0xFF || idx >= i {
+				return fmt.Errorf("account %d: duplicate index %d does not refer to an earlier account", i, idx)
+			}
+			orig := &p.Accounts[idx]
+			if orig.IsDuplicate {
+				return fmt.Errorf("account %d: duplicate index %d refers to another duplicate", i, idx)
+			}
+			if acc.IsSigner && !orig.IsSigner {
+				return fmt.Errorf("account %d: duplicate is signer but account %d is not", i, idx)
+			}
+			if acc.IsWritable && !orig.IsWritable {
+				return fmt.Errorf("account %d: duplicate is writable but account %d is not", i, idx)
+			}
 			continue
 		}
-		_ = binary.Write(buf, binary.LittleEndian, uint8(0xFF))
-		_ = binary.Write(buf, binary.LittleEndian, acc.IsSigner)
-		_ = binary.Write(buf, binary.LittleEndian, acc.IsWritable)
-		_ = binary.Write(buf, binary.LittleEndian, acc.IsExecutable)
-		_ = writeZeros(buf, 4)
-		_, _ = buf.Write(acc.Key[:])
-		_, _ = buf.Write(acc.Owner[:])
-		_ = binary.Write(buf, binary.LittleEndian, acc.Lamports)
 
-		_ = binary.Write(buf, binary.LittleEndian, uint64(len(acc.Data)))
-		// This account copy cannot be avoided without a significant redesign of the VM
-		_, _ = buf.Write(acc.Data[:])
+		if acc.dataLen() > MaxPermittedDataLength {
+			return fmt.Errorf("account %d: data length %d exceeds maximum of %d", i, acc.dataLen(), MaxPermittedDataLength)
+		}
+		if prev, ok := keys[acc.Key]; ok {
+			// Entries holding different state for the same account are
+			// a worse construction bug than a missing marker alone.
+			if field := accountStateDiff(&p.Accounts[prev], acc); field != "" {
+				return fmt.Errorf("account %d: key %s already used by account %d with different %s", i, acc.Key, prev, field)
+			}
+			return fmt.Errorf("account %d: key %s already used by account %d without duplicate marker", i, acc.Key, prev)
+		}
+		keys[acc.Key] = i
+	}
+	return nil
+}
+
+// accountStateDiff returns the name of the first account state field
+// that differs between a and b, or "" if they agree.
+func accountStateDiff(a, b *AccountParam) string {
+	switch {
+	case a.Owner != b.Owner:
+		return "owner"
+	case a.Lamports != b.Lamports:
+		return "lamports"
+	case a.dataLen() != b.dataLen() || !bytes.Equal(a.Data, b.Data):
+		return "data"
+	}
+	return ""
+}
+
+// ExpectProgramID returns an error if the params invoke a program other than id.
+func (p *Params) ExpectProgramID(id [32]byte) error {
+	if p.ProgramID != id {
+		return fmt.Errorf("program ID is %s, expected %s", p.ProgramID, solana.PublicKey(id))
+	}
+	return nil
+}
 
-		acc.Padding = ReallocSpace
-		if offset := buf.Len() % ReallocAlign; offset != 0 {
-			acc.Padding += ReallocAlign - offset
+// HasSystemProgram reports whether the system program, whose ID is the
+// all-zero key, is the invoked program or among the accounts.
+func (p *Params) HasSystemProgram() bool {
+	if base58.Pubkey(p.ProgramID).IsZero() {
+		return true
+	}
+	for i := range p.Accounts {
+		// Duplicates need not carry the key they refer to.
+		acc := &p.Accounts[i]
+		if !acc.IsDuplicate && base58.Pubkey(acc.Key).IsZero() {
+			return true
 		}
-		_ = writeZeros(buf, acc.Padding)
+	}
+	return false
+}
 
-		_ = binary.Write(buf, binary.LittleEndian, acc.RentEpoch)
+// FeePayer returns the first account, which by convention pays the
+// transaction fee, or an error if it cannot do so because it is
+// missing, not a signer, or not writable.
+func (p *Params) FeePayer() (*AccountParam, error) {
+	if len(p.Accounts) == 0 {
+		return nil, fmt.Errorf("no fee payer: no accounts")
+	}
+	acc := &p.Accounts[0]
+	switch {
+	case acc.IsDuplicate:
+		return nil, fmt.Errorf("fee payer is a duplicate marker")
+	case !acc.IsSigner:
+		return nil, fmt.Errorf("fee payer %s is not a signer", acc.Key)
+	case !acc.IsWritable:
+		return nil, fmt.Errorf("fee payer %s is not writable", acc.Key)
 	}
+	return acc, nil
+}
 
-	_ = binary.Write(buf, binary.LittleEndian, uint64(len(p.Data)))
-	_, _ = buf.Write(p.Data)
+// DataFitsPacket reports whether the instruction data fits within
+// InstructionDataBudget, along with the number of chunks of at most
+// that many bytes it would be split into for submission. Data that fits
+// takes a single chunk. If the accounts alone fill the packet, no
+// number of chunks suffices and zero is returned.
+func (p *Params) DataFitsPacket() (bool, int) {
+	budget := p.InstructionDataBudget()
+	if len(p.Data) <= budget {
+		return true, 1
+	}
+	if budget == 0 {
+		return false, 0
+	}
+	return false, (len(p.Data) + budget - 1) / budget
+}
 
-	_, err := buf.Write(p.ProgramID[:])
-	if err != nil {
-		panic("writes to buffer failed: " + err.Error()) // OOM
+// InstructionDataBudget returns how many bytes of instruction data fit
+// in a packet holding a legacy transaction with p as its only
+// instruction, after the signatures of all signer accounts, the message
+// header, account keys, recent blockhash, and instruction framing.
+// Without signer accounts, a separate fee payer is assumed to sign.
+func (p *Params) InstructionDataBudget() int {
+	signers, keys := 0, 1 // the program ID
+	for i := range p.Accounts {
+		acc := &p.Accounts[i]
+		if acc.IsDuplicate {
+			continue
+		}
+		if acc.Key != p.ProgramID {
+			keys++
+		}
+		if acc.IsSigner {
+			signers++
+		}
+	}
+	if signers == 0 {
+		signers, keys = 1, keys+1
+	}
+	overhead := compactU16Len(signers) + 64*signers +
+		3 + // message header
+		compactU16Len(keys) + 32*keys +
+		32 + // recent blockhash
+		compactU16Len(1) + 1 + // instruction count, program ID index
+		compactU16Len(len(p.Accounts)) + len(p.Accounts)
+	budget := PacketDataLen - overhead
+	budget -= compactU16Len(budget) // data length prefix
+	if budget < 0 {
+		return 0
+	}
+	return budget
+}
+
+// compactU16Len returns the encoded size of n as a compact-u16, the
+// variable-length integer used in transactions for counts and lengths.
+func compactU16Len(n int) int {
+	switch {
+	case n < 1<<7:
+		return 1
+	case n < 1<<14:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// CheckWriteAuthority returns an error if a writable non-signer account
+// is not owned by the invoked program.
+//
+// Such accounts can only be modified by their owner, so a program that
+// writes to them will fail at runtime unless it is initializing them.
+func (p *Params) CheckWriteAuthority() error {
+	for i := range p.Accounts {
+		acc := &p.Accounts[i]
+		if acc.IsDuplicate || !acc.IsWritable || acc.IsSigner {
+			continue
+		}
+		if acc.Owner != p.ProgramID {
+			return fmt.Errorf("writable account %d (%s) is owned by %s, not program %s",
+				i, acc.Key, acc.Owner, p.ProgramID)
+		}
+	}
+	return nil
+}
+
+// CheckSigners returns an error naming the first account flagged as a
+// signer whose key is not in the set of verified signers.
+func (p *Params) CheckSigners(signed map[[32]byte]bool) error {
+	for i := range p.Accounts {
+		acc, err := p.account(i)
+		if err != nil {
+			return err
+		}
+		isSigner := p.Accounts[i].IsSigner || acc.IsSigner
+		if isSigner && !signed[acc.Key] {
+			return fmt.Errorf("account %d (%s) is a signer but was not signed", i, acc.Key)
+		}
+	}
+	return nil
+}
+
+// VerifyDataChanges returns an error if the data of an account not
+// owned by the invoked program differs from its data in before, which
+// holds the params as they were prior to execution.
+//
+// Ownership is taken from before, and each aliased account is checked
+// once through its first occurrence.
+func (p *Params) VerifyDataChanges(before *Params) error {
+	if len(before.Accounts) != len(p.Accounts) {
+		return fmt.Errorf("number of accounts changed")
+	}
+	for i := range p.Accounts {
+		acc, prev := &p.Accounts[i], &before.Accounts[i]
+		if prev.IsDuplicate || prev.Owner == before.ProgramID {
+			continue
+		}
+		if !bytes.Equal(acc.Data, prev.Data) {
+			return fmt.Errorf("account %d (%s) owned by %s modified by program %s",
+				i, prev.Key, prev.Owner, before.ProgramID)
+		}
+	}
+	return nil
+}
+
+// BeginTransaction starts a new transaction for the purpose of
+// Serializer.CumulativeRealloc, so that account lengths are measured
+// from their next serialization.
+func (p *Params) BeginTransaction() {
+	for i := range p.Accounts {
+		p.Accounts[i].txStarted = false
+	}
+}
+
+// Serialize writes the params to the provided buffer.
+func (p *Params) Serialize(buf *bytes.Buffer) error {
+	buf.Reset()
+	return p.SerializeTo(buf)
+}
+
+// SerializeTo writes the params to w.
+func (p *Params) SerializeTo(w io.Writer) error {
+	_, err := p.WriteTo(w)
+	return err
+}
+
+// WriteTo serializes the params to w and returns the number of bytes
+// written, implementing io.WriterTo.
+func (p *Params) WriteTo(w io.Writer) (int64, error) {
+	if _, err := p.SerializedSize(); err != nil {
+		return 0, err
+	}
+	cw := &countingWriter{w: w}
+	_ = binary.Write(cw, binary.LittleEndian, uint64(len(p.Accounts)))
+	for i := range p.Accounts {
+		if err := p.serializeAccount(cw, i); err != nil {
+			return int64(cw.n), err
+		}
+	}
+	p.serializeTrailer(cw)
+	return int64(cw.n), cw.err
+}
+
+// SerializeOrdered writes the params to buf with the accounts arranged
+// so that the i-th serialized account is p.Accounts[order[i]].
+//
+// Duplicate markers are derived for the new order, making the first
+// occurrence of each key the full account. The params themselves are
+// left untouched, so the output cannot be read back with Update.
+func (p *Params) SerializeOrdered(buf *bytes.Buffer, order []int) error {
+	if len(order) != len(p.Accounts) {
+		return fmt.Errorf("order has %d entries for %d accounts", len(order), len(p.Accounts))
+	}
+	seen := make([]bool, len(p.Accounts))
+	accounts := make([]AccountParam, len(order))
+	for i, idx := range order {
+		if idx < 0 || idx >= len(p.Accounts) || seen[idx] {
+			return fmt.Errorf("order is not a permutation: index %d at position %d", idx, i)
+		}
+		seen[idx] = true
+		acc, err := p.account(idx)
+		if err != nil {
+			return err
+		}
+		accounts[i] = *acc
+	}
+	if err := MarkDuplicates(accounts); err != nil {
+		return err
+	}
+	reordered := *p
+	reordered.Accounts = accounts
+	return reordered.Serialize(buf)
+}
+
+// SerializeIncremental serializes the params one piece at a time,
+// allowing the caller to apply backpressure between pieces.
+//
+// Each call to next returns the following chunk: first the account
+// count, then one chunk per account, and finally the instruction data
+// and program ID. The concatenated chunks equal the output of Serialize.
+// A chunk is only valid until the next call. Once next reports false,
+// err returns the error that stopped serialization, if any.
+//
+// Params exceeding MaxInputRegionSize fail before the first chunk.
+// Reading account data from a DataSource can still fail midway, after
+// earlier chunks were handed out, which is why errors are reported
+// separately rather than by next alone.
+func (p *Params) SerializeIncremental() (next func() ([]byte, bool), err func() error) {
+	var buf bytes.Buffer
+	cw := &countingWriter{w: &buf}
+	_, failed := p.SerializedSize()
+	stage := -1
+	next = func() ([]byte, bool) {
+		if failed != nil || stage > len(p.Accounts) {
+			return nil, false
+		}
+		buf.Reset()
+		switch {
+		case stage < 0:
+			_ = binary.Write(cw, binary.LittleEndian, uint64(len(p.Accounts)))
+		case stage < len(p.Accounts):
+			failed = p.serializeAccount(cw, stage)
+		default:
+			p.serializeTrailer(cw)
+		}
+		stage++
+		if failed == nil {
+			failed = cw.err
+		}
+		if failed != nil {
+			return nil, false
+		}
+		return buf.Bytes(), true
+	}
+	err = func() error {
+		return failed
+	}
+	return next, err
+}
+
+// serializeAccount writes the account at index i.
+func (p *Params) serializeAccount(cw *countingWriter, i int) error {
+	return p.writeAccount(cw, &p.Accounts[i], i)
+}
+
+// writeAccount writes acc as the account at index i, recording its
+// layout in acc.
+func (p *Params) writeAccount(cw *countingWriter, acc *AccountParam, i int) error {
+	if acc.IsDuplicate {
+		_, _ = cw.Write([]byte{acc.DuplicateIndex})
+		_ = writeZeros(cw, 7)
+		return nil
+	}
+	if acc.Data == nil && acc.DataSource == nil && acc.DataLen > 0 {
+		return fmt.Errorf("account %d: data of length %d not loaded", i, acc.DataLen)
+	}
+	_ = binary.Write(cw, binary.LittleEndian, uint8(0xFF))
+	if err := writeAccountFlags(cw, acc, p.serializer().FlagsLayout); err != nil {
+		return err
+	}
+	_, _ = cw.Write(acc.Key[:])
+	_, _ = cw.Write(acc.Owner[:])
+	_ = binary.Write(cw, binary.LittleEndian, acc.Lamports)
+
+	acc.originalDataLen = acc.dataLen()
+	if !acc.txStarted {
+		acc.txStartDataLen = acc.originalDataLen
+		acc.txStarted = true
+	}
+	_ = binary.Write(cw, binary.LittleEndian, uint64(acc.originalDataLen))
+	if acc.Data == nil && acc.DataSource != nil {
+		src := io.NewSectionReader(acc.DataSource, 0, int64(acc.DataLen))
+		if _, err := io.CopyN(cw, src, int64(acc.DataLen)); err != nil {
+			return fmt.Errorf("account %d data: %w", i, err)
+		}
+	} else {
+		// This account copy cannot be avoided without a significant redesign of the VM
+		_, _ = cw.Write(acc.Data)
 	}
+
+	acc.Padding = reallocPadding(cw.n)
+	_ = writeZeros(cw, acc.Padding)
+
+	_ = binary.Write(cw, binary.LittleEndian, acc.RentEpoch)
+	return nil
+}
+
+// serializeTrailer writes the instruction data, program ID, and
+// execution context.
+func (p *Params) serializeTrailer(cw *countingWriter) {
+	if p.serializer().InstructionContext {
+		_, _ = cw.Write([]byte{p.InstructionIndex, p.StackHeight, 0, 0, 0, 0, 0, 0})
+	}
+	_ = binary.Write(cw, binary.LittleEndian, uint64(len(p.Data)))
+	_, _ = cw.Write(p.Data)
+
+	_, _ = cw.Write(p.ProgramID[:])
+
+	if p.Context != nil {
+		p.Context.writeTo(cw)
+	}
+}
+
+// SerializedSize returns the number of bytes written by Serialize, and
+// an error if that exceeds MaxInputRegionSize.
+func (p *Params) SerializedSize() (int, error) {
+	n := p.ProgramIDOffset() + 32
+	if p.Context != nil {
+		n += executionContextLen
+	}
+	if int64(n) > MaxInputRegionSize {
+		return n, fmt.Errorf("serialized params of %d bytes exceed maximum input region size of %d", n, int64(MaxInputRegionSize))
+	}
+	return n, nil
+}
+
+// ProgramIDOffset returns the offset of the program ID in the output of
+// Serialize. Only the execution context, if any, follows the program ID.
+func (p *Params) ProgramIDOffset() int {
+	return p.walkAccounts(nil) + p.instructionContextLen() + 8 + len(p.Data)
+}
+
+// instructionContextLen returns the size of the instruction context.
+func (p *Params) instructionContextLen() int {
+	if p.serializer().InstructionContext {
+		return 8
+	}
+	return 0
+}
+
+// DataOffsets returns the offset of each account's data within the
+// serialized params, or -1 for duplicate accounts.
+func (p *Params) DataOffsets() []int {
+	offsets := make([]int, len(p.Accounts))
+	for i := range offsets {
+		offsets[i] = -1
+	}
+	p.walkAccounts(func(i, dataOffset int) {
+		offsets[i] = dataOffset
+	})
+	return offsets
+}
+
+// walkAccounts computes the serialized layout of the accounts.
+// It calls fn (if non-nil) with the data offset of each non-duplicate
+// account, and returns the offset following the last account.
+func (p *Params) walkAccounts(fn func(i, dataOffset int)) int {
+	n := 8
+	for i := range p.Accounts {
+		acc := &p.Accounts[i]
+		if acc.IsDuplicate {
+			n += 8
+			continue
+		}
+		n += 8 + 32 + 32 + 8 + 8
+		if fn != nil {
+			fn(i, n)
+		}
+		n += acc.dataLen()
+		n += reallocPadding(n)
+		n += 8
+	}
+	return n
 }
 
-// Update writes data modified by a program back to the params struct.
+// reallocPadding returns the number of bytes reserved after account data
+// ending at the given offset: the realloc space, plus enough to align
+// the following field.
+func reallocPadding(offset int) int {
+	padding := ReallocSpace
+	if rem := offset % ReallocAlign; rem != 0 {
+		padding += ReallocAlign - rem
+	}
+	return padding
+}
+
+// UpdateOptions selects the checks Update applies to program output.
+//
+// Structural checks, such as the account count and order, data length
+// limits, and monotonic rent epochs, are always applied.
+type UpdateOptions uint
+
+const (
+	// CheckLamports rejects lamport balances that wrapped around, a
+	// changed lamport total, and accounts closed with non-zero data.
+	CheckLamports UpdateOptions = 1 << iota
+
+	// CheckOwner permits an owner change only for writable,
+	// non-executable accounts owned by the invoked program whose data
+	// is zeroed.
+	CheckOwner
+
+	// CheckExecutable rejects changes to the executable flag and to
+	// the data of executable accounts, except for the deployment
+	// account of a Serializer with DeploymentContext set.
+	CheckExecutable
+
+	// CheckPadding requires the realloc region past both the original
+	// and the new data length to be left zeroed.
+	CheckPadding
+
+	// CheckFlags rejects changes to the signer and writable flags.
+	CheckFlags
+
+	// CheckProgramID rejects a changed program ID.
+	CheckProgramID
+)
+
+// DefaultUpdateOptions are the checks applied by Update and
+// UpdateWritable.
+const DefaultUpdateOptions = CheckLamports | CheckExecutable
+
+// Update writes data modified by a program back to the params struct,
+// applying DefaultUpdateOptions.
 func (p *Params) Update(buf *bytes.Reader) error {
-	// TODO authorization checks
+	return p.update(buf, false, DefaultUpdateOptions)
+}
 
-	for i := 0; true; i++ {
-		if i >= len(p.Accounts) {
-			return fmt.Errorf("number of accounts changed")
+// UpdateOpts is like Update, but applies only the checks selected by
+// checks in addition to the structural ones.
+func (p *Params) UpdateOpts(buf *bytes.Reader, checks UpdateOptions) error {
+	return p.update(buf, false, checks)
+}
+
+// UpdateWritable is like Update, but only reads back writable accounts.
+// Read-only accounts, which the program cannot legally modify, are
+// skipped. In strict mode, their serialized form is compared against
+// the buffer instead, and any modification is an error.
+func (p *Params) UpdateWritable(buf *bytes.Reader) error {
+	return p.update(buf, true, DefaultUpdateOptions)
+}
+
+// ValidateUpdate performs all checks of Update on buf without applying
+// it to p, returning the first violation. The read position of buf is
+// restored afterwards, so the same reader can be passed to Update once
+// validation passes.
+func (p *Params) ValidateUpdate(buf *bytes.Reader) error {
+	return p.ValidateUpdateOpts(buf, DefaultUpdateOptions)
+}
+
+// ValidateUpdateOpts is like ValidateUpdate, but performs the checks of
+// UpdateOpts with the given checks.
+func (p *Params) ValidateUpdateOpts(buf *bytes.Reader, checks UpdateOptions) error {
+	pos, _ := buf.Seek(0, io.SeekCurrent)
+	defer buf.Seek(pos, io.SeekStart)
+
+	// Update replaces account data rather than writing into it, so a
+	// shallow copy of the accounts keeps p intact.
+	dry := *p
+	dry.Accounts = append([]AccountParam(nil), p.Accounts...)
+	if p.Serializer != nil && p.Serializer.Audit != nil {
+		opts := *p.Serializer
+		opts.Audit = nil
+		dry.Serializer = &opts
+	}
+	if p.Context != nil {
+		ctx := *p.Context
+		dry.Context = &ctx
+	}
+	return dry.UpdateOpts(buf, checks)
+}
+
+func (p *Params) update(buf *bytes.Reader, writableOnly bool, checks UpdateOptions) error {
+	opts := p.serializer()
+	var preLamports uint64
+	if checks&CheckLamports != 0 {
+		var err error
+		if preLamports, err = p.TotalLamportsChecked(); err != nil {
+			return fmt.Errorf("lamports before execution: %w", err)
 		}
+	}
+
+	var audit []AuditAccount
+
+	var count uint64
+	if err := binary.Read(buf, binary.LittleEndian, &count); err != nil {
+		return fmt.Errorf("account count: %w", err)
+	}
+	if count != uint64(len(p.Accounts)) {
+		return fmt.Errorf("serialized account count %d does not match %d accounts", count, len(p.Accounts))
+	}
+
+	for i := range p.Accounts {
 		acc := &p.Accounts[i]
 
 		idx, err := buf.ReadByte()
 		if err != nil {
-			return err
+			return fmt.Errorf("number of accounts changed")
 		}
-		if (!acc.IsDuplicate && idx != 0xFF) || acc.DuplicateIndex != idx {
-			return fmt.Errorf("account order changed")
+		if acc.IsDuplicate {
+			if idx != acc.DuplicateIndex {
+				return fmt.Errorf("account order changed")
+			}
+			_, _ = buf.Seek(7, io.SeekCurrent)
+			continue
 		}
-
 		if idx != 0xFF {
+			return fmt.Errorf("account order changed")
+		}
+		if writableOnly && !acc.IsWritable {
+			if err := p.skipAccount(buf, i); err != nil {
+				return err
+			}
 			continue
 		}
 
+		prev := *acc
+		if err := readAccountFlags(buf, acc, opts.FlagsLayout); err != nil {
+			return err
+		}
+		if checks&CheckFlags != 0 && (acc.IsSigner != prev.IsSigner || acc.IsWritable != prev.IsWritable) {
+			return fmt.Errorf("signer or writable flag of account %d changed", i)
+		}
 		// TODO is deferring error check okay here?
-		_ = binary.Read(buf, binary.LittleEndian, &acc.IsSigner)
-		_ = binary.Read(buf, binary.LittleEndian, &acc.IsWritable)
-		_ = binary.Read(buf, binary.LittleEndian, &acc.IsExecutable)
-		_, _ = buf.Seek(4, io.SeekCurrent)
 		_, _ = buf.Read(acc.Key[:])
 		_, _ = buf.Read(acc.Owner[:])
 		_ = binary.Read(buf, binary.LittleEndian, &acc.Lamports)
 
-		oldLen := uint64(len(acc.Data))
+		// A single account cannot hold more than all accounts combined
+		// held before execution. Anything larger is most likely the
+		// result of an unsigned subtraction wrapping around.
+		if checks&CheckLamports != 0 && acc.Lamports > preLamports {
+			return fmt.Errorf("account %d lamports %d exceed total of %d before execution (underflow?)",
+				i, acc.Lamports, preLamports)
+		}
+
+		// Shrinking is always permitted. The data region reserved by the
+		// serializer spans the original length plus the realloc padding.
+		origLen := uint64(acc.originalDataLen)
 		var newLen uint64
 		_ = binary.Read(buf, binary.LittleEndian, &newLen)
-		if newLen < oldLen {
-			return fmt.Errorf("attempted to shrink account")
+		if newLen > origLen+ReallocSpace {
+			return fmt.Errorf("account %d data length %d exceeds original length %d plus realloc limit",
+				i, newLen, origLen)
 		}
-		if newLen > oldLen+ReallocSpace {
-			return fmt.Errorf("attempted to grow account too much")
+		if opts.CumulativeRealloc && newLen > uint64(acc.txStartDataLen)+ReallocSpace {
+			return fmt.Errorf("account %d data length %d exceeds length %d at transaction start plus realloc limit",
+				i, newLen, acc.txStartDataLen)
+		}
+		if newLen > MaxPermittedDataLength {
+			return fmt.Errorf("account %d data length %d exceeds maximum of %d",
+				i, newLen, MaxPermittedDataLength)
 		}
 		acc.Data, _ = io.ReadAll(io.LimitReader(buf, int64(newLen)))
-		_, _ = buf.Seek(int64(acc.Padding-int(newLen-oldLen)), io.SeekCurrent)
+		if uint64(len(acc.Data)) != newLen {
+			return fmt.Errorf("account %d data truncated: got %d, want %d", i, len(acc.Data), newLen)
+		}
+		rest := int64(acc.originalDataLen+acc.Padding) - int64(newLen)
+		if checks&CheckPadding != 0 {
+			region := make([]byte, rest)
+			_, _ = io.ReadFull(buf, region)
+			if unclaimed := int64(acc.originalDataLen) - int64(newLen); unclaimed > 0 {
+				region = region[unclaimed:]
+			}
+			if !isZero(region) {
+				return fmt.Errorf("realloc padding of account %d modified", i)
+			}
+		} else {
+			_, _ = buf.Seek(rest, io.SeekCurrent)
+		}
+
+		deploying := opts.DeploymentContext && opts.DeploymentAccount == i
+		if checks&CheckExecutable != 0 && !deploying {
+			if acc.IsExecutable != prev.IsExecutable {
+				return fmt.Errorf("executable flag of account %d changed", i)
+			}
+			// Streamed data is not kept around for comparison.
+			dataKnown := prev.Data != nil || acc.DataSource == nil
+			if prev.IsExecutable && dataKnown && !bytes.Equal(prev.Data, acc.Data) {
+				return fmt.Errorf("data of executable account %d modified", i)
+			}
+		}
+
+		if checks&CheckOwner != 0 && acc.Owner != prev.Owner {
+			switch {
+			case !prev.IsWritable:
+				return fmt.Errorf("owner of read-only account %d changed", i)
+			case prev.Owner != p.ProgramID:
+				return fmt.Errorf("owner of account %d changed, but it is not owned by the program", i)
+			case prev.IsExecutable:
+				return fmt.Errorf("owner of executable account %d changed", i)
+			case !isZero(acc.Data):
+				return fmt.Errorf("owner of account %d changed with non-zero data", i)
+			}
+		}
 
+		// Draining all lamports closes the account, which must not
+		// leave data behind.
+		if checks&CheckLamports != 0 && prev.Lamports != 0 && acc.Lamports == 0 && !isZero(acc.Data) {
+			return fmt.Errorf("account %d closed with non-zero data", i)
+		}
+
+		// Rent epochs only move forward, except that a rent-exempt
+		// account (rent epoch u64::MAX) may be given any epoch.
+		prevRentEpoch := acc.RentEpoch
 		_ = binary.Read(buf, binary.LittleEndian, &acc.RentEpoch)
+		if prevRentEpoch != math.MaxUint64 && acc.RentEpoch < prevRentEpoch {
+			return fmt.Errorf("account %d rent epoch decreased from %d to %d", i, prevRentEpoch, acc.RentEpoch)
+		}
+
+		if opts.Audit != nil {
+			if rec, modified := auditAccount(i, &prev, acc); modified {
+				audit = append(audit, rec)
+			}
+		}
 	}
 
-	_, _ = buf.Seek(int64(len(p.Data)), io.SeekCurrent)
-	_, err := buf.Read(p.ProgramID[:])
-	return err
+	if checks&CheckLamports != 0 {
+		postLamports, err := p.TotalLamportsChecked()
+		if err != nil {
+			return fmt.Errorf("lamports after execution: %w", err)
+		}
+		if postLamports != preLamports {
+			return fmt.Errorf("sum of account lamports changed: %d before, %d after", preLamports, postLamports)
+		}
+	}
+
+	if !p.serializer().Strict {
+		_, _ = buf.Seek(int64(p.instructionContextLen()+8+len(p.Data)), io.SeekCurrent)
+	} else if err := p.checkInstructionData(buf); err != nil {
+		return err
+	}
+	var programID solana.PublicKey
+	if _, err := io.ReadFull(buf, programID[:]); err != nil {
+		return err
+	}
+	if checks&CheckProgramID != 0 && programID != p.ProgramID {
+		return fmt.Errorf("program ID changed from %s to %s", p.ProgramID, programID)
+	}
+	p.ProgramID = programID
+	if p.Context != nil {
+		if err := p.Context.readFrom(buf); err != nil {
+			return err
+		}
+	}
+	if opts.Audit != nil {
+		opts.Audit.Record(AuditEntry{
+			Time:      time.Now(),
+			ProgramID: base58.Pubkey(p.ProgramID).String(),
+			Accounts:  audit,
+		})
+	}
+	return nil
+}
+
+// checkInstructionData reads the instruction data region and verifies
+// that it still matches p.Data, which programs must not modify.
+func (p *Params) checkInstructionData(buf *bytes.Reader) error {
+	if _, err := buf.Seek(int64(p.instructionContextLen()), io.SeekCurrent); err != nil {
+		return err
+	}
+	var dataLen uint64
+	if err := binary.Read(buf, binary.LittleEndian, &dataLen); err != nil {
+		return err
+	}
+	if dataLen != uint64(len(p.Data)) {
+		return fmt.Errorf("instruction data length changed from %d to %d", len(p.Data), dataLen)
+	}
+	data := make([]byte, dataLen)
+	if _, err := io.ReadFull(buf, data); err != nil {
+		return err
+	}
+	if !bytes.Equal(data, p.Data) {
+		return fmt.Errorf("instruction data modified")
+	}
+	return nil
+}
+
+// skipAccount advances buf past the account at index i, whose duplicate
+// marker has already been read. In strict mode, it verifies that the
+// skipped bytes match the serialized account.
+func (p *Params) skipAccount(buf *bytes.Reader, i int) error {
+	acc := &p.Accounts[i]
+	size := 7 + 32 + 32 + 8 + 8 + acc.originalDataLen + acc.Padding + 8
+	if !p.serializer().Strict {
+		_, err := buf.Seek(int64(size), io.SeekCurrent)
+		return err
+	}
+
+	// Write a copy, so that checking leaves the layout of acc alone.
+	start := int(buf.Size()) - buf.Len() - 1
+	var want bytes.Buffer
+	cp := *acc
+	if err := p.writeAccount(&countingWriter{w: &want, n: start}, &cp, i); err != nil {
+		return err
+	}
+	got := make([]byte, size)
+	if _, err := io.ReadFull(buf, got); err != nil {
+		return err
+	}
+	if !bytes.Equal(got, want.Bytes()[1:]) {
+		return fmt.Errorf("read-only account %d modified", i)
+	}
+	return nil
+}
+
+// TotalLamports returns the lamports held by all non-duplicate accounts,
+// saturating at math.MaxUint64 on overflow.
+func (p *Params) TotalLamports() uint64 {
+	sum, err := p.TotalLamportsChecked()
+	if err != nil {
+		return math.MaxUint64
+	}
+	return sum
+}
+
+// TotalLamportsChecked returns the lamports held by all non-duplicate
+// accounts, or an error if the sum overflows.
+func (p *Params) TotalLamportsChecked() (uint64, error) {
+	var sum uint64
+	for i := range p.Accounts {
+		acc := &p.Accounts[i]
+		if acc.IsDuplicate {
+			continue
+		}
+		var err error
+		sum, err = safemath.CheckedAddU64(sum, acc.Lamports)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return sum, nil
+}
+
+// LamportsDeltas returns the change in lamports of each account
+// relative to before, which holds the params prior to execution.
+//
+// Duplicate entries report zero, as their change is reported by the
+// account they refer to. Deltas beyond the int64 range saturate.
+func (p *Params) LamportsDeltas(before *Params) []int64 {
+	deltas := make([]int64, len(p.Accounts))
+	for i := range p.Accounts {
+		if i >= len(before.Accounts) || p.Accounts[i].IsDuplicate {
+			continue
+		}
+		deltas[i] = lamportsDelta(before.Accounts[i].Lamports, p.Accounts[i].Lamports)
+	}
+	return deltas
 }
 
-func writeZeros(b *bytes.Buffer, n int) error {
-	_, err := io.Copy(b, io.LimitReader(zeroRd{}, int64(n)))
+// lamportsDelta returns post-pre, saturated to the int64 range.
+func lamportsDelta(pre, post uint64) int64 {
+	switch {
+	case post >= pre && post-pre > math.MaxInt64:
+		return math.MaxInt64
+	case post >= pre:
+		return int64(post - pre)
+	case pre-post > 1<<63:
+		return math.MinInt64
+	default:
+		return -int64(pre - post)
+	}
+}
+
+// CreatedAccounts returns the indices of accounts that appear to have
+// been created, relative to before, which holds the params prior to
+// execution. An account counts as created if it was funded from zero
+// lamports or assigned away from the system program.
+func (p *Params) CreatedAccounts(before *Params) []int {
+	var created []int
+	for i := range p.Accounts {
+		if i >= len(before.Accounts) || p.Accounts[i].IsDuplicate {
+			continue
+		}
+		post, pre := &p.Accounts[i], &before.Accounts[i]
+		funded := pre.Lamports == 0 && post.Lamports != 0
+		assigned := pre.Owner == solana.SystemProgramID && post.Owner != solana.SystemProgramID
+		if funded || assigned {
+			created = append(created, i)
+		}
+	}
+	return created
+}
+
+// isZero returns whether b contains only zero bytes.
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func writeZeros(w io.Writer, n int) error {
+	_, err := io.Copy(w, io.LimitReader(zeroRd{}, int64(n)))
 	return err
 }
 
+// countingWriter counts the bytes written and remembers the first error,
+// after which all writes fail.
+type countingWriter struct {
+	w   io.Writer
+	n   int
+	err error
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(b)
+	c.n += n
+	c.err = err
+	return n, err
+}
+
 type zeroRd struct{}
 
 func (zeroRd) Read(buf []byte) (int, error) {
@@ -0,0 +1,1470 @@
+package sealevel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"unsafe"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// testParams returns a small layout with two distinct accounts,
+// a duplicate reference, and some instruction data.
+func testParams() Params {
+	return Params{
+		Accounts: []AccountParam{
+			{
+				IsSigner:   true,
+				IsWritable: true,
+				Key:        [32]byte{1},
+				Owner:      [32]byte{9},
+				Lamports:   100,
+				Data:       []byte{1, 2, 3},
+			},
+			{
+				IsWritable: true,
+				Key:        [32]byte{2},
+				Owner:      [32]byte{9},
+				Lamports:   50,
+				Data:       make([]byte, 16),
+				RentEpoch:  math.MaxUint64,
+			},
+			{
+				IsDuplicate:    true,
+				DuplicateIndex: 0,
+			},
+		},
+		Data:      []byte("instruction"),
+		ProgramID: [32]byte{9},
+	}
+}
+
+// lamportsOffset returns the offset of the lamports field of the
+// account serialized at accountOffset.
+func lamportsOffset(accountOffset int) int {
+	return accountOffset + 8 + 32 + 32
+}
+
+func TestParams_Update_Roundtrip(t *testing.T) {
+	params := testParams()
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+
+	before := testParams()
+	require.NoError(t, params.Update(bytes.NewReader(buf.Bytes())))
+	for i := range before.Accounts {
+		assert.Equal(t, before.Accounts[i].Lamports, params.Accounts[i].Lamports)
+		assert.Equal(t, len(before.Accounts[i].Data), len(params.Accounts[i].Data))
+	}
+	assert.Equal(t, before.ProgramID, params.ProgramID)
+}
+
+func TestParams_Update_LamportsWrapped(t *testing.T) {
+	params := testParams()
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	out := buf.Bytes()
+
+	// Account 0 underflows by 110 lamports while account 1 is credited
+	// 110 lamports. The wrapped sum still matches the original total.
+	acc0 := 8
+	acc1 := acc0 + 8 + 32 + 32 + 8 + 8 + len(params.Accounts[0].Data) + params.Accounts[0].Padding + 8
+	binary.LittleEndian.PutUint64(out[lamportsOffset(acc0):], math.MaxUint64-9) // 100 - 110
+	binary.LittleEndian.PutUint64(out[lamportsOffset(acc1):], 50+110)
+
+	err := params.Update(bytes.NewReader(out))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "account 0 lamports")
+}
+
+func TestParams_Update_LamportsNotConserved(t *testing.T) {
+	params := testParams()
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	out := buf.Bytes()
+
+	binary.LittleEndian.PutUint64(out[lamportsOffset(8):], 99)
+
+	err := params.Update(bytes.NewReader(out))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "sum of account lamports changed")
+}
+
+func TestParams_Validate(t *testing.T) {
+	assert.NoError(t, (&Params{}).Validate())
+	params := testParams()
+	assert.NoError(t, params.Validate())
+
+	cases := []struct {
+		name   string
+		modify func(p *Params)
+		err    string
+	}{
+		{
+			name:   "DuplicateIndexForward",
+			modify: func(p *Params) { p.Accounts[2].DuplicateIndex = 2 },
+			err:    "does not refer to an earlier account",
+		},
+		{
+			name:   "DuplicateIndexInvalid",
+			modify: func(p *Params) { p.Accounts[2].DuplicateIndex = 0xFF },
+			err:    "does not refer to an earlier account",
+		},
+		{
+			name: "DuplicateOfDuplicate",
+			modify: func(p *Params) {
+				p.Accounts = append(p.Accounts, AccountParam{IsDuplicate: true, DuplicateIndex: 2})
+			},
+			err: "refers to another duplicate",
+		},
+		{
+			name: "DuplicateSigner",
+			modify: func(p *Params) {
+				p.Accounts[2].DuplicateIndex = 1
+				p.Accounts[2].IsSigner = true
+			},
+			err: "duplicate is signer",
+		},
+		{
+			name: "DuplicateWritable",
+			modify: func(p *Params) {
+				p.Accounts[1].IsWritable = false
+				p.Accounts[2].DuplicateIndex = 1
+				p.Accounts[2].IsWritable = true
+			},
+			err: "duplicate is writable",
+		},
+		{
+			name:   "DataTooLarge",
+			modify: func(p *Params) { p.Accounts[1].Data = make([]byte, MaxPermittedDataLength+1) },
+			err:    "exceeds maximum",
+		},
+		{
+			name:   "KeyReused",
+			modify: func(p *Params) { p.Accounts[1].Key = p.Accounts[0].Key },
+			err:    "already used by account 0",
+		},
+		{
+			name: "KeyReusedWithDifferentData",
+			modify: func(p *Params) {
+				p.Accounts[1].Key = p.Accounts[0].Key
+				p.Accounts[1].Owner = p.Accounts[0].Owner
+				p.Accounts[1].Lamports = p.Accounts[0].Lamports
+			},
+			err: "already used by account 0 with different data",
+		},
+		{
+			name: "KeyReusedWithDifferentLamports",
+			modify: func(p *Params) {
+				p.Accounts[1].Key = p.Accounts[0].Key
+				p.Accounts[1].Data = p.Accounts[0].Data
+			},
+			err: "already used by account 0 with different lamports",
+		},
+		{
+			name: "KeyReusedWithSameState",
+			modify: func(p *Params) {
+				p.Accounts[1] = p.Accounts[0]
+			},
+			err: "already used by account 0 without duplicate marker",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			params := testParams()
+			tc.modify(&params)
+			err := params.Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+func TestParams_SerializeTo_DataSource(t *testing.T) {
+	params := testParams()
+	var want bytes.Buffer
+	require.NoError(t, params.Serialize(&want))
+
+	data := params.Accounts[1].Data
+	params.Accounts[1].Data = nil
+	params.Accounts[1].DataSource = bytes.NewReader(data)
+	params.Accounts[1].DataLen = len(data)
+	var got bytes.Buffer
+	require.NoError(t, params.SerializeTo(&got))
+	assert.Equal(t, want.Bytes(), got.Bytes())
+
+	// Update reads the account data back into Data.
+	require.NoError(t, params.Update(bytes.NewReader(got.Bytes())))
+	assert.Equal(t, data, params.Accounts[1].Data)
+}
+
+func TestParams_SerializeTo_DataSourceShort(t *testing.T) {
+	params := testParams()
+	params.Accounts[1].Data = nil
+	params.Accounts[1].DataSource = bytes.NewReader(make([]byte, 4))
+	params.Accounts[1].DataLen = 8
+	assert.Error(t, params.SerializeTo(io.Discard))
+}
+
+func TestParams_SerializedSize(t *testing.T) {
+	for _, params := range []Params{{}, testParams(), benchParams(16, 1000)} {
+		var buf bytes.Buffer
+		require.NoError(t, params.Serialize(&buf))
+		assert.Equal(t, buf.Len(), serializedSize(t, &params))
+	}
+}
+
+// serializedSize returns the serialized size of p, failing the test if
+// it is out of bounds.
+func serializedSize(tb testing.TB, p *Params) int {
+	tb.Helper()
+	n, err := p.SerializedSize()
+	require.NoError(tb, err)
+	return n
+}
+
+// benchParams returns params with n accounts holding dataLen bytes each.
+func benchParams(n int, dataLen int) Params {
+	params := Params{Data: make([]byte, 32)}
+	for i := 0; i < n; i++ {
+		params.Accounts = append(params.Accounts, AccountParam{
+			IsWritable: true,
+			Key:        [32]byte{byte(i), byte(i >> 8)},
+			Lamports:   1,
+			Data:       make([]byte, dataLen),
+		})
+	}
+	return params
+}
+
+func BenchmarkParams_Serialize(b *testing.B) {
+	layouts := []struct {
+		name     string
+		accounts int
+		dataLen  int
+	}{
+		{"Small", 2, 0},
+		{"Medium", 16, 1024},
+		{"Large", 64, 1024 * 1024},
+	}
+	for _, layout := range layouts {
+		params := benchParams(layout.accounts, layout.dataLen)
+		for _, preGrow := range []bool{false, true} {
+			b.Run(fmt.Sprintf("%s/PreGrow=%t", layout.name, preGrow), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					var buf bytes.Buffer
+					if preGrow {
+						buf.Grow(serializedSize(b, &params))
+					}
+					if err := params.SerializeTo(&buf); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestParams_DataOffsets(t *testing.T) {
+	params := testParams()
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	out := buf.Bytes()
+
+	offsets := params.DataOffsets()
+	require.Len(t, offsets, len(params.Accounts))
+	for i, offset := range offsets {
+		acc := &params.Accounts[i]
+		if acc.IsDuplicate {
+			assert.Equal(t, -1, offset)
+			continue
+		}
+		dataLen := binary.LittleEndian.Uint64(out[offset-8:])
+		assert.Equal(t, uint64(len(acc.Data)), dataLen)
+		assert.Equal(t, acc.Data, out[offset:offset+len(acc.Data)])
+	}
+}
+
+func TestParams_AccountFlags(t *testing.T) {
+	params := Params{
+		Accounts: []AccountParam{{IsSigner: true, IsExecutable: true}},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+
+	// The flags follow the account count and the duplicate marker.
+	assert.Equal(t, []byte{0xFF, 1, 0, 1, 0, 0, 0, 0}, buf.Bytes()[8:16])
+
+	// Programs cannot change the executable flag, keep it.
+	params.Accounts[0] = AccountParam{IsExecutable: true}
+	require.NoError(t, params.Update(bytes.NewReader(buf.Bytes())))
+	assert.True(t, params.Accounts[0].IsSigner)
+	assert.False(t, params.Accounts[0].IsWritable)
+	assert.True(t, params.Accounts[0].IsExecutable)
+
+	params.Serializer = &Serializer{FlagsLayout: 0xFF}
+	assert.Error(t, params.Serialize(&buf))
+}
+
+func TestParams_Serialize_Deterministic(t *testing.T) {
+	params := testParams()
+	size := serializedSize(t, &params)
+
+	var outputs [][]byte
+	for _, capacity := range []int{0, size, 10 * size} {
+		var buf bytes.Buffer
+		buf.Grow(capacity)
+		require.NoError(t, params.Serialize(&buf))
+		outputs = append(outputs, buf.Bytes())
+	}
+	for _, out := range outputs[1:] {
+		assert.Equal(t, outputs[0], out)
+	}
+}
+
+func TestParams_CheckProgramData(t *testing.T) {
+	programID := solana.PublicKey{7}
+	programData, err := ProgramDataAddress(programID)
+	require.NoError(t, err)
+
+	// Accounts as passed to the loader-v3 Upgrade instruction.
+	params := Params{
+		Accounts: []AccountParam{
+			{
+				IsWritable: true,
+				Key:        programData,
+				Owner:      solana.BPFLoaderUpgradeableProgramID,
+				Lamports:   1000,
+				Data:       make([]byte, 45),
+			},
+			{
+				IsWritable:   true,
+				IsExecutable: true,
+				Key:          programID,
+				Owner:        solana.BPFLoaderUpgradeableProgramID,
+				Lamports:     10,
+				Data:         UpgradeableProgramData(programData),
+			},
+			{
+				IsSigner: true,
+				Key:      solana.PublicKey{8},
+			},
+		},
+		ProgramID: solana.BPFLoaderUpgradeableProgramID,
+	}
+	require.NoError(t, params.Validate())
+	require.NoError(t, params.CheckProgramData(1, 0))
+	assert.Error(t, params.CheckProgramData(0, 1))
+	assert.Error(t, params.CheckProgramData(1, 2))
+	assert.Error(t, params.CheckProgramData(1, 3))
+
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	offsets := params.DataOffsets()
+	assert.Equal(t, programData[:], buf.Bytes()[offsets[1]+4:offsets[1]+36])
+
+	params.Accounts[1].Data = UpgradeableProgramData(solana.PublicKey{9})
+	assert.Error(t, params.CheckProgramData(1, 0))
+}
+
+func TestMarkDuplicates(t *testing.T) {
+	keys := []byte{1, 2, 1, 3, 2, 1}
+	accounts := make([]AccountParam, len(keys))
+	for i, k := range keys {
+		accounts[i].Key = solana.PublicKey{k}
+	}
+	// Stale markers are overwritten.
+	accounts[1].IsDuplicate = true
+
+	require.NoError(t, MarkDuplicates(accounts))
+	want := []struct {
+		dup bool
+		idx uint8
+	}{{false, 0}, {false, 0}, {true, 0}, {false, 0}, {true, 1}, {true, 0}}
+	for i, w := range want {
+		assert.Equal(t, w.dup, accounts[i].IsDuplicate, "account %d", i)
+		assert.Equal(t, w.idx, accounts[i].DuplicateIndex, "account %d", i)
+	}
+	params := Params{Accounts: accounts}
+	assert.NoError(t, params.Validate())
+}
+
+func TestMarkDuplicates_IndexTooLarge(t *testing.T) {
+	accounts := make([]AccountParam, 257)
+	for i := 0; i < 256; i++ {
+		accounts[i].Key = solana.PublicKey{byte(i), byte(i >> 8)}
+	}
+	accounts[256].Key = accounts[254].Key
+	require.NoError(t, MarkDuplicates(accounts))
+
+	accounts[256].Key = accounts[255].Key
+	assert.Error(t, MarkDuplicates(accounts))
+}
+
+func TestParams_SerializeIncremental(t *testing.T) {
+	params := testParams()
+	var want bytes.Buffer
+	require.NoError(t, params.Serialize(&want))
+
+	next, errFn := params.SerializeIncremental()
+	var got []byte
+	var chunks int
+	for chunk, ok := next(); ok; chunk, ok = next() {
+		got = append(got, chunk...)
+		chunks++
+	}
+	require.NoError(t, errFn())
+	assert.Equal(t, want.Bytes(), got)
+	assert.Equal(t, len(params.Accounts)+2, chunks)
+
+	_, ok := next()
+	assert.False(t, ok)
+}
+
+func TestParams_SerializeIncremental_Error(t *testing.T) {
+	params := testParams()
+	params.Accounts[1].Data = nil
+	params.Accounts[1].DataSource = bytes.NewReader(nil)
+	params.Accounts[1].DataLen = 1
+
+	next, errFn := params.SerializeIncremental()
+	var chunks int
+	for _, ok := next(); ok; _, ok = next() {
+		chunks++
+	}
+	assert.Equal(t, 2, chunks)
+	assert.Error(t, errFn())
+
+	// Oversized params fail before the first chunk, like Serialize.
+	var huge Params
+	for i := 0; i < MaxInputRegionSize/MaxPermittedDataLength+1; i++ {
+		huge.Accounts = append(huge.Accounts, AccountParam{
+			Key:     [32]byte{byte(i), byte(i >> 8)},
+			DataLen: MaxPermittedDataLength,
+		})
+	}
+	next, errFn = huge.SerializeIncremental()
+	_, ok := next()
+	assert.False(t, ok)
+	require.Error(t, errFn())
+	assert.Contains(t, errFn().Error(), "exceed maximum input region size")
+}
+
+func TestParams_ExpectProgramID(t *testing.T) {
+	params := testParams()
+	assert.NoError(t, params.ExpectProgramID([32]byte{9}))
+
+	err := params.ExpectProgramID([32]byte{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), solana.PublicKey{9}.String())
+	assert.Contains(t, err.Error(), "11111111111111111111111111111111")
+}
+
+func TestParams_InstructionContext(t *testing.T) {
+	params := testParams()
+	params.InstructionIndex = 3
+	params.StackHeight = 2
+	var plain bytes.Buffer
+	require.NoError(t, params.Serialize(&plain))
+	accountsEnd := plain.Len() - 32 - len(params.Data) - 8
+
+	params.Serializer = &Serializer{InstructionContext: true}
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	out := buf.Bytes()
+	require.Equal(t, plain.Len()+8, len(out))
+	assert.Equal(t, len(out), serializedSize(t, &params))
+
+	assert.Equal(t, plain.Bytes()[:accountsEnd], out[:accountsEnd])
+	assert.Equal(t, []byte{3, 2, 0, 0, 0, 0, 0, 0}, out[accountsEnd:accountsEnd+8])
+	assert.Equal(t, plain.Bytes()[accountsEnd:], out[accountsEnd+8:])
+
+	require.NoError(t, params.Update(bytes.NewReader(out)))
+	assert.Equal(t, solana.PublicKey{9}, params.ProgramID)
+}
+
+func TestParams_UpdateWritable(t *testing.T) {
+	params := testParams()
+	params.Accounts[1].IsWritable = false
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	out := buf.Bytes()
+
+	// Modify read-only data and writable data.
+	offsets := params.DataOffsets()
+	out[offsets[0]] = 0xAA
+	out[offsets[1]] = 0xBB
+
+	require.NoError(t, params.UpdateWritable(bytes.NewReader(out)))
+	assert.Equal(t, byte(0xAA), params.Accounts[0].Data[0])
+	assert.Equal(t, make([]byte, 16), params.Accounts[1].Data)
+
+	params.Serializer = &Serializer{Strict: true}
+	err := params.UpdateWritable(bytes.NewReader(out))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "read-only account 1 modified")
+
+	out[offsets[1]] = 0
+	require.NoError(t, params.UpdateWritable(bytes.NewReader(out)))
+
+	// The comparison leaves the skipped account as it was.
+	params.Accounts[1].Data = make([]byte, 17)
+	before := params.Accounts[1]
+	require.Error(t, params.UpdateWritable(bytes.NewReader(out)))
+	assert.Equal(t, before, params.Accounts[1])
+}
+
+func TestParams_TotalLamports(t *testing.T) {
+	params := testParams()
+	// The duplicate of account 0 is counted once.
+	assert.Equal(t, uint64(150), params.TotalLamports())
+	sum, err := params.TotalLamportsChecked()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(150), sum)
+
+	params.Accounts[1].Lamports = math.MaxUint64
+	_, err = params.TotalLamportsChecked()
+	assert.Error(t, err)
+	assert.Equal(t, uint64(math.MaxUint64), params.TotalLamports())
+}
+
+func TestParams_Update_ShrinkData(t *testing.T) {
+	params := testParams()
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	out := buf.Bytes()
+	lenOffset := params.DataOffsets()[1] - 8
+
+	// Shrink to zero.
+	binary.LittleEndian.PutUint64(out[lenOffset:], 0)
+	require.NoError(t, params.Update(bytes.NewReader(out)))
+	assert.Empty(t, params.Accounts[1].Data)
+
+	// Re-grow within the same instruction, up to the original
+	// length plus the realloc limit.
+	binary.LittleEndian.PutUint64(out[lenOffset:], 16+ReallocSpace)
+	require.NoError(t, params.Update(bytes.NewReader(out)))
+	assert.Len(t, params.Accounts[1].Data, 16+ReallocSpace)
+
+	binary.LittleEndian.PutUint64(out[lenOffset:], 16+ReallocSpace+1)
+	err := params.Update(bytes.NewReader(out))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds original length")
+}
+
+func TestParams_CheckWriteAuthority(t *testing.T) {
+	params := testParams()
+	require.NoError(t, params.CheckWriteAuthority())
+
+	params.Accounts[1].Owner = solana.PublicKey{8}
+	err := params.CheckWriteAuthority()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), solana.PublicKey{2}.String())
+
+	// Read-only and signer accounts are exempt.
+	params.Accounts[1].IsWritable = false
+	require.NoError(t, params.CheckWriteAuthority())
+	params.Accounts[1].IsWritable = true
+	params.Accounts[1].IsSigner = true
+	require.NoError(t, params.CheckWriteAuthority())
+}
+
+func TestParams_AccountsFingerprint(t *testing.T) {
+	a, b := testParams(), testParams()
+	b.Accounts[0].Data = []byte{7, 7}
+	b.Accounts[1].Lamports = 1
+	b.Data = nil
+	assert.Equal(t, a.AccountsFingerprint(), b.AccountsFingerprint())
+
+	b.Accounts[1].IsWritable = false
+	assert.NotEqual(t, a.AccountsFingerprint(), b.AccountsFingerprint())
+
+	b = testParams()
+	b.Accounts[2].DuplicateIndex = 1
+	assert.NotEqual(t, a.AccountsFingerprint(), b.AccountsFingerprint())
+}
+
+func TestParams_CanonicalHash(t *testing.T) {
+	a := testParams()
+	b := testParams()
+	b.Accounts = []AccountParam{
+		a.Accounts[1],
+		a.Accounts[0],
+		{IsDuplicate: true, DuplicateIndex: 1},
+	}
+	assert.Equal(t, a.CanonicalHash(), b.CanonicalHash())
+	assert.NotEqual(t, a.AccountsFingerprint(), b.AccountsFingerprint())
+
+	b.Accounts[1].Data = []byte{1, 2, 4}
+	assert.NotEqual(t, a.CanonicalHash(), b.CanonicalHash())
+
+	b = testParams()
+	b.Data = []byte("other")
+	assert.NotEqual(t, a.CanonicalHash(), b.CanonicalHash())
+}
+
+func TestParams_VerifyDataChanges(t *testing.T) {
+	before := testParams()
+	before.Accounts[1].Owner = solana.PublicKey{8}
+
+	params := testParams()
+	params.Accounts[1].Owner = solana.PublicKey{8}
+	params.Accounts[0].Data[0] = 0xAA
+	require.NoError(t, params.VerifyDataChanges(&before))
+
+	params.Accounts[1].Data[0] = 0xBB
+	err := params.VerifyDataChanges(&before)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "account 1")
+}
+
+func TestParams_WriteTSV(t *testing.T) {
+	params := testParams()
+	var buf bytes.Buffer
+	require.NoError(t, params.WriteTSV(&buf))
+
+	golden := filepath.Join("testdata", "accounts.tsv")
+	if *update {
+		require.NoError(t, os.WriteFile(golden, buf.Bytes(), 0644))
+	}
+	want, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), buf.String())
+}
+
+func TestParams_Update_StrictInstructionData(t *testing.T) {
+	params := testParams()
+	params.Serializer = &Serializer{Strict: true}
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	out := buf.Bytes()
+	require.NoError(t, params.Update(bytes.NewReader(out)))
+
+	dataOffset := len(out) - 32 - len(params.Data)
+	out[dataOffset] ^= 0xFF
+	err := params.Update(bytes.NewReader(out))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "instruction data modified")
+
+	// Without strict mode, the region is skipped.
+	params.Serializer = nil
+	require.NoError(t, params.Update(bytes.NewReader(out)))
+}
+
+func TestParams_ProgramIDOffset(t *testing.T) {
+	params := testParams()
+	params.Serializer = &Serializer{InstructionContext: true}
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+
+	off := params.ProgramIDOffset()
+	assert.Equal(t, buf.Len()-32, off)
+	assert.Equal(t, params.ProgramID[:], buf.Bytes()[off:off+32])
+}
+
+func TestParams_ExecutionContext(t *testing.T) {
+	params := testParams()
+	params.Context = &ExecutionContext{Slot: 123, Epoch: 4, FeePayer: solana.PublicKey{1}}
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	out := buf.Bytes()
+	assert.Equal(t, serializedSize(t, &params), len(out))
+	assert.Equal(t, params.ProgramID[:], out[params.ProgramIDOffset():params.ProgramIDOffset()+32])
+
+	binary.LittleEndian.PutUint64(out[len(out)-48:], 124)
+	require.NoError(t, params.Update(bytes.NewReader(out)))
+	assert.Equal(t, ExecutionContext{Slot: 124, Epoch: 4, FeePayer: solana.PublicKey{1}}, *params.Context)
+
+	got := Params{Context: new(ExecutionContext)}
+	require.NoError(t, DeserializeInto(&got, out))
+	assert.Equal(t, params.Context, got.Context)
+	assert.Equal(t, params.ProgramID, got.ProgramID)
+
+	// Without a context, the trailing bytes are rejected.
+	_, err := Deserialize(out)
+	assert.Error(t, err)
+}
+
+func TestParams_DataLenPlaceholder(t *testing.T) {
+	params := testParams()
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+
+	planned := testParams()
+	planned.Accounts[1].Data = nil
+	planned.Accounts[1].DataLen = 16
+	assert.Equal(t, buf.Len(), serializedSize(t, &planned))
+	assert.Equal(t, params.DataOffsets(), planned.DataOffsets())
+	assert.Equal(t, params.ProgramIDOffset(), planned.ProgramIDOffset())
+
+	err := planned.Serialize(&buf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not loaded")
+
+	planned.Accounts[1].Data = make([]byte, 16)
+	require.NoError(t, planned.Serialize(&buf))
+}
+
+func TestParams_CumulativeRealloc(t *testing.T) {
+	params := testParams()
+	params.Serializer = &Serializer{CumulativeRealloc: true}
+
+	// grow serializes the params for the next instruction and grows
+	// account 1 by n bytes.
+	grow := func(n int) error {
+		var buf bytes.Buffer
+		require.NoError(t, params.Serialize(&buf))
+		out := buf.Bytes()
+		lenOffset := params.DataOffsets()[1] - 8
+		binary.LittleEndian.PutUint64(out[lenOffset:], uint64(len(params.Accounts[1].Data)+n))
+		return params.Update(bytes.NewReader(out))
+	}
+
+	require.NoError(t, grow(ReallocSpace/2))
+	assert.Len(t, params.Accounts[1].Data, 16+ReallocSpace/2)
+	// Each grow is within the per-instruction limit, but together
+	// they exceed the limit for the transaction.
+	err := grow(ReallocSpace/2 + 1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "transaction start")
+
+	// Without cumulative accounting, only the per-instruction limit applies.
+	params.Serializer = nil
+	require.NoError(t, grow(ReallocSpace/2+1))
+
+	// A new transaction measures from the current length.
+	params.Serializer = &Serializer{CumulativeRealloc: true}
+	params.BeginTransaction()
+	require.NoError(t, grow(ReallocSpace))
+}
+
+func TestParams_Update_ClosedAccount(t *testing.T) {
+	params := testParams()
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	out := buf.Bytes()
+	offsets := params.DataOffsets()
+
+	// Move all lamports of account 0 to account 1.
+	binary.LittleEndian.PutUint64(out[offsets[0]-16:], 0)
+	binary.LittleEndian.PutUint64(out[offsets[1]-16:], 150)
+	err := params.Update(bytes.NewReader(out))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "account 0 closed with non-zero data")
+
+	// Closing properly truncates the data.
+	params = testParams()
+	require.NoError(t, params.Serialize(new(bytes.Buffer)))
+	binary.LittleEndian.PutUint64(out[offsets[0]-8:], 0)
+	require.NoError(t, params.Update(bytes.NewReader(out)))
+	assert.Empty(t, params.Accounts[0].Data)
+	assert.Zero(t, params.Accounts[0].Lamports)
+}
+
+func TestParams_Update_RentEpoch(t *testing.T) {
+	update := func(epoch uint64) (*Params, error) {
+		params := testParams()
+		params.Accounts[0].RentEpoch = 10
+		var buf bytes.Buffer
+		require.NoError(t, params.Serialize(&buf))
+		out := buf.Bytes()
+		acc := params.Accounts[0]
+		binary.LittleEndian.PutUint64(out[params.DataOffsets()[0]+len(acc.Data)+acc.Padding:], epoch)
+		return &params, params.Update(bytes.NewReader(out))
+	}
+
+	params, err := update(11)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(11), params.Accounts[0].RentEpoch)
+
+	_, err = update(10)
+	require.NoError(t, err)
+
+	_, err = update(9)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "account 0 rent epoch decreased from 10 to 9")
+
+	// Rent-exempt accounts may move to any epoch.
+	exempt := testParams()
+	var buf bytes.Buffer
+	require.NoError(t, exempt.Serialize(&buf))
+	out := buf.Bytes()
+	acc := exempt.Accounts[1]
+	binary.LittleEndian.PutUint64(out[exempt.DataOffsets()[1]+len(acc.Data)+acc.Padding:], 3)
+	require.NoError(t, exempt.Update(bytes.NewReader(out)))
+	assert.Equal(t, uint64(3), exempt.Accounts[1].RentEpoch)
+}
+
+func TestParams_SerializeOrdered(t *testing.T) {
+	params := testParams()
+	var buf bytes.Buffer
+	require.NoError(t, params.SerializeOrdered(&buf, []int{0, 1, 2}))
+	var want bytes.Buffer
+	require.NoError(t, params.Serialize(&want))
+	assert.Equal(t, want.Bytes(), buf.Bytes())
+
+	// Moving the duplicate first makes it the full account.
+	require.NoError(t, params.SerializeOrdered(&buf, []int{2, 1, 0}))
+	got, err := Deserialize(buf.Bytes())
+	require.NoError(t, err)
+	require.Len(t, got.Accounts, 3)
+	assert.False(t, got.Accounts[0].IsDuplicate)
+	assert.Equal(t, solana.PublicKey{1}, got.Accounts[0].Key)
+	assert.Equal(t, []byte{1, 2, 3}, got.Accounts[0].Data)
+	assert.Equal(t, solana.PublicKey{2}, got.Accounts[1].Key)
+	assert.True(t, got.Accounts[2].IsDuplicate)
+	assert.Equal(t, uint8(0), got.Accounts[2].DuplicateIndex)
+
+	for _, order := range [][]int{{0, 1}, {0, 1, 1}, {0, 1, 3}, {-1, 1, 2}} {
+		assert.Error(t, params.SerializeOrdered(&buf, order), "order %v", order)
+	}
+}
+
+func TestParams_HasSystemProgram(t *testing.T) {
+	params := testParams()
+	assert.False(t, params.HasSystemProgram())
+
+	params.Accounts[1].Key = solana.SystemProgramID
+	assert.True(t, params.HasSystemProgram())
+
+	params = testParams()
+	params.ProgramID = solana.PublicKey{}
+	assert.True(t, params.HasSystemProgram())
+}
+
+func TestParams_DataFitsPacket(t *testing.T) {
+	// One signature, three keys including the program ID, and three
+	// account indices take 203 bytes, plus 2 for the data length.
+	params := testParams()
+	budget := params.InstructionDataBudget()
+	assert.Equal(t, PacketDataLen-203-2, budget)
+
+	for _, tc := range []struct {
+		len    int
+		fits   bool
+		chunks int
+	}{
+		{0, true, 1},
+		{budget - 1, true, 1},
+		{budget, true, 1},
+		{budget + 1, false, 2},
+		{2 * budget, false, 2},
+		{2*budget + 1, false, 3},
+	} {
+		params.Data = make([]byte, tc.len)
+		fits, chunks := params.DataFitsPacket()
+		assert.Equal(t, tc.fits, fits, "length %d", tc.len)
+		assert.Equal(t, tc.chunks, chunks, "length %d", tc.len)
+	}
+
+	// Too many accounts leave no room for data at all.
+	for i := 0; i < 40; i++ {
+		params.Accounts = append(params.Accounts, AccountParam{Key: [32]byte{byte(i), 1}})
+	}
+	assert.Zero(t, params.InstructionDataBudget())
+	fits, chunks := params.DataFitsPacket()
+	assert.False(t, fits)
+	assert.Zero(t, chunks)
+}
+
+func TestParams_CheckSigners(t *testing.T) {
+	params := testParams()
+	require.NoError(t, params.CheckSigners(map[[32]byte]bool{{1}: true}))
+
+	params.Accounts[1].IsSigner = true
+	err := params.CheckSigners(map[[32]byte]bool{{1}: true})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), solana.PublicKey{2}.String())
+
+	err = params.CheckSigners(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "account 0")
+}
+
+func TestParams_WriteTo(t *testing.T) {
+	params := testParams()
+	var want bytes.Buffer
+	require.NoError(t, params.Serialize(&want))
+
+	var wt io.WriterTo = &params
+	var buf bytes.Buffer
+	n, err := wt.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(want.Len()), n)
+	assert.Equal(t, want.Bytes(), buf.Bytes())
+
+	var got Params
+	var rf io.ReaderFrom = &got
+	n, err = rf.ReadFrom(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(want.Len()), n)
+	assert.Equal(t, params.Data, got.Data)
+	assert.Equal(t, params.Accounts[1].Data, got.Accounts[1].Data)
+}
+
+func TestParams_AccountMetas(t *testing.T) {
+	params := testParams()
+	key0 := solana.PublicKey{1}.String()
+	assert.Equal(t, []AccountMeta{
+		{Pubkey: key0, Signer: true, Writable: true},
+		{Pubkey: solana.PublicKey{2}.String(), Writable: true},
+		{Pubkey: key0, Signer: true, Writable: true},
+	}, params.AccountMetas())
+}
+
+func TestParams_Update_DeploymentContext(t *testing.T) {
+	serialize := func(params *Params) []byte {
+		var buf bytes.Buffer
+		require.NoError(t, params.Serialize(&buf))
+		return buf.Bytes()
+	}
+
+	// Deploying writes the program data and marks it executable.
+	params := testParams()
+	out := serialize(&params)
+	out[8+1+2] = 1 // executable flag of account 0
+	out[params.DataOffsets()[0]] = 0xAA
+	err := params.Update(bytes.NewReader(out))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "executable flag of account 0 changed")
+
+	params = testParams()
+	params.Serializer = &Serializer{DeploymentContext: true, DeploymentAccount: 0}
+	serialize(&params)
+	require.NoError(t, params.Update(bytes.NewReader(out)))
+	assert.True(t, params.Accounts[0].IsExecutable)
+	assert.Equal(t, byte(0xAA), params.Accounts[0].Data[0])
+
+	// Once executable, the data is immutable outside of deployments.
+	params.Serializer = nil
+	out = serialize(&params)
+	out[params.DataOffsets()[0]] = 0xBB
+	err = params.Update(bytes.NewReader(out))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "data of executable account 0 modified")
+}
+
+func TestParams_MemoryFootprint(t *testing.T) {
+	params := Params{
+		Accounts: make([]AccountParam, 2, 4),
+		Data:     make([]byte, 10, 16),
+		Context:  &ExecutionContext{},
+	}
+	params.Accounts[0].Data = make([]byte, 100, 128)
+	params.Accounts[1] = AccountParam{IsDuplicate: true, Data: params.Accounts[0].Data}
+
+	// The duplicate aliases the data of account 0, counted once.
+	want := int(unsafe.Sizeof(params)) +
+		4*int(unsafe.Sizeof(AccountParam{})) +
+		128 + 16 +
+		8 + 8 + 32
+	assert.Equal(t, want, params.MemoryFootprint())
+}
+
+func TestParams_Canonicalize(t *testing.T) {
+	want := testParams()
+	var wantBuf bytes.Buffer
+	require.NoError(t, want.Serialize(&wantBuf))
+
+	// Reference account 0 by a second full entry instead of a duplicate.
+	params := testParams()
+	params.Accounts[2] = params.Accounts[0]
+	params.Accounts[2].IsSigner = false
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	assert.NotEqual(t, wantBuf.Bytes(), buf.Bytes())
+
+	require.NoError(t, params.Canonicalize())
+	require.NoError(t, params.Serialize(&buf))
+	assert.Equal(t, wantBuf.Bytes(), buf.Bytes())
+	assert.True(t, params.Accounts[2].IsDuplicate)
+
+	// Privileges of later occurrences are merged into the first.
+	params = testParams()
+	params.Accounts[0].IsWritable = false
+	params.Accounts[2].IsWritable = true
+	require.NoError(t, params.Canonicalize())
+	assert.True(t, params.Accounts[0].IsWritable)
+
+	params = Params{Accounts: make([]AccountParam, 257)}
+	for i := 0; i < 256; i++ {
+		params.Accounts[i].Key = solana.PublicKey{byte(i), byte(i >> 8)}
+	}
+	params.Accounts[256].Key = params.Accounts[255].Key
+	assert.Error(t, params.Canonicalize())
+	assert.False(t, params.Accounts[256].IsDuplicate)
+}
+
+func TestParams_LamportsDeltas(t *testing.T) {
+	before := testParams()
+	params := testParams()
+	params.Accounts[0].Lamports -= 30
+	params.Accounts[1].Lamports += 30
+	assert.Equal(t, []int64{-30, 30, 0}, params.LamportsDeltas(&before))
+
+	params.Accounts[1].Lamports = math.MaxUint64
+	params.Accounts[0].Lamports = 0
+	before.Accounts[0].Lamports = math.MaxUint64
+	assert.Equal(t, []int64{math.MinInt64, math.MaxInt64, 0}, params.LamportsDeltas(&before))
+}
+
+func TestParams_Update_AccountCount(t *testing.T) {
+	params := testParams()
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	out := buf.Bytes()
+
+	for _, count := range []uint64{2, 4} {
+		binary.LittleEndian.PutUint64(out, count)
+		err := params.Update(bytes.NewReader(out))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), fmt.Sprintf("serialized account count %d does not match 3 accounts", count))
+	}
+	binary.LittleEndian.PutUint64(out, 3)
+	require.NoError(t, params.Update(bytes.NewReader(out)))
+}
+
+func TestParams_UpdateWithByteDiff(t *testing.T) {
+	params := testParams()
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	out := buf.Bytes()
+	offsets := params.DataOffsets()
+
+	// Change bytes 4-5 and 9 of account 1, leaving account 0 alone.
+	out[offsets[1]+4] = 0xAA
+	out[offsets[1]+5] = 0xBB
+	out[offsets[1]+9] = 0xCC
+
+	diffs, err := params.UpdateWithByteDiff(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, map[int][]ByteRange{1: {{4, 6}, {9, 10}}}, diffs)
+
+	// Growing an account reports the new tail.
+	params = testParams()
+	buf.Reset()
+	require.NoError(t, params.Serialize(&buf))
+	out = buf.Bytes()
+	binary.LittleEndian.PutUint64(out[offsets[0]-8:], 5)
+	out[offsets[0]+2] = 9
+	diffs, err = params.UpdateWithByteDiff(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, map[int][]ByteRange{0: {{2, 5}}}, diffs)
+
+	// So does growing an account from no data at all.
+	params = testParams()
+	params.Accounts[1].Data = nil
+	buf.Reset()
+	require.NoError(t, params.Serialize(&buf))
+	out = buf.Bytes()
+	offsets = params.DataOffsets()
+	binary.LittleEndian.PutUint64(out[offsets[1]-8:], 4)
+	out[offsets[1]] = 7
+	diffs, err = params.UpdateWithByteDiff(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, map[int][]ByteRange{1: {{0, 4}}}, diffs)
+	assert.Equal(t, []byte{7, 0, 0, 0}, params.Accounts[1].Data)
+}
+
+func TestParams_MaxInputRegionSize(t *testing.T) {
+	// Fill the region with data length placeholders, which do not
+	// allocate, and top it up exactly with instruction data.
+	var params Params
+	for i := 0; ; i++ {
+		params.Accounts = append(params.Accounts, AccountParam{
+			Key:     [32]byte{byte(i), byte(i >> 8)},
+			DataLen: MaxPermittedDataLength,
+		})
+		if n, err := params.SerializedSize(); err != nil || MaxInputRegionSize-n < MaxPermittedDataLength+ReallocSpace+1024 {
+			break
+		}
+	}
+	n, err := params.SerializedSize()
+	require.NoError(t, err)
+	params.Data = make([]byte, MaxInputRegionSize-n)
+
+	n, err = params.SerializedSize()
+	require.NoError(t, err)
+	assert.Equal(t, MaxInputRegionSize, n)
+
+	params.Data = append(params.Data, 0)
+	_, err = params.SerializedSize()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceed maximum input region size")
+
+	// Serialize fails up front, before any account is written.
+	var buf bytes.Buffer
+	err = params.Serialize(&buf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceed maximum input region size")
+	assert.Zero(t, buf.Len())
+}
+
+func TestParams_ValidateUpdate(t *testing.T) {
+	params := testParams()
+	params.Context = &ExecutionContext{Slot: 1}
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	out := buf.Bytes()
+	offsets := params.DataOffsets()
+	snapshot := func(p *Params) Params {
+		c := *p
+		c.Accounts = append([]AccountParam(nil), p.Accounts...)
+		for i := range c.Accounts {
+			c.Accounts[i].Data = append([]byte(nil), p.Accounts[i].Data...)
+		}
+		ctx := *p.Context
+		c.Context = &ctx
+		return c
+	}
+	before := snapshot(&params)
+
+	// A valid buffer changing data and context leaves p alone, and can
+	// then be applied with Update.
+	out[offsets[1]] = 0xAA
+	binary.LittleEndian.PutUint64(out[len(out)-48:], 2)
+	rd := bytes.NewReader(out)
+	require.NoError(t, params.ValidateUpdate(rd))
+	assert.Equal(t, before, snapshot(&params))
+	require.NoError(t, params.Update(rd))
+	assert.Equal(t, byte(0xAA), params.Accounts[1].Data[0])
+	assert.Equal(t, uint64(2), params.Context.Slot)
+
+	// An invalid buffer leaves p alone too.
+	params = testParams()
+	params.Context = &ExecutionContext{Slot: 1}
+	require.NoError(t, params.Serialize(&buf))
+	out = buf.Bytes()
+	before = snapshot(&params)
+	out[offsets[1]] = 0xAA
+	binary.LittleEndian.PutUint64(out[lamportsOffset(8):], 99)
+	err := params.ValidateUpdate(bytes.NewReader(out))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "sum of account lamports changed")
+	assert.Equal(t, before, snapshot(&params))
+}
+
+func TestParams_Serialize_Alignment(t *testing.T) {
+	for _, dataLen := range []int{0, 1, 7, 8, 9, 1000} {
+		params := testParams()
+		params.Accounts[0].Data = make([]byte, dataLen)
+		var buf bytes.Buffer
+		require.NoError(t, params.Serialize(&buf))
+
+		for i, offset := range params.DataOffsets() {
+			acc := &params.Accounts[i]
+			if acc.IsDuplicate {
+				continue
+			}
+			assert.Zero(t, offset%ReallocAlign, "data length %d: account %d data offset %d", dataLen, i, offset)
+			rentEpoch := offset + len(acc.Data) + acc.Padding
+			assert.Zero(t, rentEpoch%ReallocAlign, "data length %d: account %d rent epoch offset %d", dataLen, i, rentEpoch)
+			assert.GreaterOrEqual(t, acc.Padding, ReallocSpace)
+			assert.Less(t, acc.Padding, ReallocSpace+ReallocAlign)
+		}
+		assert.Zero(t, (params.ProgramIDOffset()-len(params.Data)-8)%ReallocAlign, "data length %d: accounts end misaligned", dataLen)
+	}
+}
+
+func TestParams_CreatedAccounts(t *testing.T) {
+	before := testParams()
+	before.Accounts[1].Lamports = 0
+	before.Accounts[1].Owner = solana.SystemProgramID
+
+	// Account 1 is funded, account 0 merely modified.
+	params := testParams()
+	params.Accounts[0].Data = []byte{4, 5, 6}
+	params.Accounts[0].Lamports = 50
+	params.Accounts[1].Owner = solana.SystemProgramID
+	assert.Equal(t, []int{1}, params.CreatedAccounts(&before))
+
+	// Assigning an unfunded account to a program also creates it.
+	params = testParams()
+	params.Accounts[1].Lamports = 0
+	assert.Equal(t, []int{1}, params.CreatedAccounts(&before))
+
+	assert.Empty(t, before.CreatedAccounts(&before))
+}
+
+func TestParams_UpdateOpts(t *testing.T) {
+	layout := testParams()
+	require.NoError(t, layout.Serialize(new(bytes.Buffer)))
+	offsets := layout.DataOffsets()
+	account1 := offsets[1] - (8 + 32 + 32 + 8 + 8)
+
+	all := CheckLamports | CheckOwner | CheckExecutable | CheckPadding | CheckFlags | CheckProgramID
+	cases := []struct {
+		check  UpdateOptions
+		modify func(out []byte)
+		err    string
+	}{
+		{CheckLamports, func(out []byte) { binary.LittleEndian.PutUint64(out[lamportsOffset(8):], 99) }, "sum of account lamports changed"},
+		{CheckOwner, func(out []byte) { out[8+8+32] = 7 }, "owner of account 0 changed with non-zero data"},
+		{CheckExecutable, func(out []byte) { out[account1+3] = 1 }, "executable flag of account 1 changed"},
+		{CheckPadding, func(out []byte) { out[offsets[1]+16+100] = 1 }, "realloc padding of account 1 modified"},
+		{CheckFlags, func(out []byte) { out[account1+1] = 1 }, "signer or writable flag of account 1 changed"},
+		{CheckProgramID, func(out []byte) { out[layout.ProgramIDOffset()] = 8 }, "program ID changed"},
+	}
+	for _, tc := range cases {
+		run := func(checks UpdateOptions) error {
+			params := testParams()
+			var buf bytes.Buffer
+			require.NoError(t, params.Serialize(&buf))
+			out := buf.Bytes()
+			tc.modify(out)
+			return params.UpdateOpts(bytes.NewReader(out), checks)
+		}
+		assert.NoError(t, run(0), tc.err)
+		assert.NoError(t, run(all&^tc.check), tc.err)
+		err := run(tc.check)
+		if assert.Error(t, err, tc.err) {
+			assert.Contains(t, err.Error(), tc.err)
+		}
+	}
+}
+
+func TestParams_ValidateUpdateOpts(t *testing.T) {
+	params := testParams()
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	out := buf.Bytes()
+	out[params.ProgramIDOffset()] = 8
+	rd := bytes.NewReader(out)
+
+	require.NoError(t, params.ValidateUpdate(rd))
+	err := params.ValidateUpdateOpts(rd, CheckProgramID)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "program ID changed")
+	assert.Equal(t, testParams().ProgramID, params.ProgramID)
+
+	binary.LittleEndian.PutUint64(out[lamportsOffset(8):], 99)
+	assert.Error(t, params.ValidateUpdate(rd))
+	assert.NoError(t, params.ValidateUpdateOpts(rd, 0))
+}
+
+func TestParams_CanonicalAccounts(t *testing.T) {
+	params := testParams()
+	params.Accounts = append(params.Accounts,
+		AccountParam{Key: solana.PublicKey{3}},
+		AccountParam{IsDuplicate: true, DuplicateIndex: 1},
+	)
+	var indices []int
+	var keys []solana.PublicKey
+	params.CanonicalAccounts(func(i int, acc *AccountParam) {
+		assert.Same(t, &params.Accounts[i], acc)
+		indices = append(indices, i)
+		keys = append(keys, acc.Key)
+	})
+	assert.Equal(t, []int{0, 1, 3}, indices)
+	assert.Equal(t, []solana.PublicKey{{1}, {2}, {3}}, keys)
+}
+
+func TestParams_Update_TruncatedData(t *testing.T) {
+	params := testParams()
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	out := buf.Bytes()
+
+	// Cut the buffer in the middle of the data of account 1.
+	out = out[:params.DataOffsets()[1]+5]
+	err := params.Update(bytes.NewReader(out))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "account 1 data truncated: got 5, want 16")
+}
+
+func TestParams_FeePayer(t *testing.T) {
+	params := testParams()
+	payer, err := params.FeePayer()
+	require.NoError(t, err)
+	assert.Same(t, &params.Accounts[0], payer)
+
+	params.Accounts[0].IsSigner = false
+	_, err = params.FeePayer()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not a signer")
+
+	params = testParams()
+	params.Accounts[0].IsWritable = false
+	_, err = params.FeePayer()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not writable")
+
+	_, err = (&Params{}).FeePayer()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no accounts")
+}
+
+func TestParams_AccountByKey(t *testing.T) {
+	params := testParams()
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			params.BuildIndex()
+		}
+		i, acc, ok := params.AccountByKey(solana.PublicKey{2})
+		require.True(t, ok, "indexed=%t", indexed)
+		assert.Equal(t, 1, i)
+		assert.Same(t, &params.Accounts[1], acc)
+
+		_, _, ok = params.AccountByKey(solana.PublicKey{7})
+		assert.False(t, ok, "indexed=%t", indexed)
+	}
+
+	// A stale index does not return the wrong account.
+	params.Accounts[0], params.Accounts[1] = params.Accounts[1], params.Accounts[0]
+	i, _, ok := params.AccountByKey(solana.PublicKey{2})
+	require.True(t, ok)
+	assert.Equal(t, 0, i)
+
+	// Nor a duplicate marker that now sits at the indexed position,
+	// even if it carries the key.
+	params = testParams()
+	params.BuildIndex()
+	params.Accounts[0], params.Accounts[2] = params.Accounts[2], params.Accounts[0]
+	params.Accounts[0].Key = params.Accounts[2].Key
+	params.Accounts[0].DuplicateIndex = 2
+	i, acc, ok := params.AccountByKey(solana.PublicKey{1})
+	require.True(t, ok)
+	assert.Equal(t, 2, i)
+	assert.False(t, acc.IsDuplicate)
+
+	// Accounts added after BuildIndex are found by a scan.
+	params = testParams()
+	params.BuildIndex()
+	params.Accounts = append(params.Accounts, AccountParam{Key: solana.PublicKey{7}})
+	i, _, ok = params.AccountByKey(solana.PublicKey{7})
+	require.True(t, ok)
+	assert.Equal(t, 3, i)
+
+	// Canonicalize and deserializing into params drop the index.
+	params.BuildIndex()
+	require.NoError(t, params.Canonicalize())
+	assert.Nil(t, params.index)
+	params.BuildIndex()
+	src := testParams()
+	var buf bytes.Buffer
+	require.NoError(t, src.Serialize(&buf))
+	require.NoError(t, DeserializeInto(&params, buf.Bytes()))
+	assert.Nil(t, params.index)
+}
+
+func BenchmarkParams_AccountByKey(b *testing.B) {
+	params := benchParams(256, 0)
+	keys := make([]solana.PublicKey, len(params.Accounts))
+	missing := make([]solana.PublicKey, len(params.Accounts))
+	for i := range keys {
+		keys[i] = params.Accounts[i].Key
+		missing[i] = keys[i]
+		missing[i][31] ^= 0xFF
+	}
+	lookup := func(keys []solana.PublicKey, want bool) func(b *testing.B) {
+		return func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, _, ok := params.AccountByKey(keys[i%len(keys)]); ok != want {
+					b.Fatalf("found = %t, want %t", ok, want)
+				}
+			}
+		}
+	}
+	b.Run("Scan/Hit", lookup(keys, true))
+	b.Run("Scan/Miss", lookup(missing, false))
+	params.BuildIndex()
+	b.Run("Index/Hit", lookup(keys, true))
+	b.Run("Index/Miss", lookup(missing, false))
+}
+
+type auditLog []AuditEntry
+
+func (l *auditLog) Record(e AuditEntry) { *l = append(*l, e) }
+
+func TestParams_Update_Audit(t *testing.T) {
+	var log auditLog
+	params := testParams()
+	params.Serializer = &Serializer{Audit: &log}
+	var buf bytes.Buffer
+	require.NoError(t, params.Serialize(&buf))
+	out := buf.Bytes()
+	offsets := params.DataOffsets()
+	account1 := offsets[1] - (8 + 32 + 32 + 8 + 8)
+
+	// Move 10 lamports from account 0 to account 1.
+	binary.LittleEndian.PutUint64(out[lamportsOffset(8):], 90)
+	binary.LittleEndian.PutUint64(out[lamportsOffset(account1):], 60)
+
+	require.NoError(t, params.ValidateUpdate(bytes.NewReader(out)))
+	assert.Empty(t, log)
+
+	require.NoError(t, params.Update(bytes.NewReader(out)))
+	require.Len(t, log, 1)
+	entry := log[0]
+	assert.False(t, entry.Time.IsZero())
+	assert.Equal(t, params.ProgramID.String(), entry.ProgramID)
+	assert.Equal(t, []AuditAccount{
+		{Index: 0, Key: params.Accounts[0].Key.String(), LamportsDelta: -10},
+		{Index: 1, Key: params.Accounts[1].Key.String(), LamportsDelta: 10},
+	}, entry.Accounts)
+
+	// Deltas beyond the int64 range saturate, matching LamportsDeltas.
+	before := params
+	before.Accounts = append([]AccountParam(nil), params.Accounts...)
+	buf.Reset()
+	require.NoError(t, params.Serialize(&buf))
+	out = buf.Bytes()
+	binary.LittleEndian.PutUint64(out[lamportsOffset(account1):], math.MaxUint64)
+	require.NoError(t, params.UpdateOpts(bytes.NewReader(out), 0))
+	require.Len(t, log, 2)
+	assert.Equal(t, []AuditAccount{
+		{Index: 1, Key: params.Accounts[1].Key.String(), LamportsDelta: math.MaxInt64},
+	}, log[1].Accounts)
+	assert.Equal(t, params.LamportsDeltas(&before)[1], log[1].Accounts[0].LamportsDelta)
+}
+
+func TestParams_RentDeltaForRealloc(t *testing.T) {
+	assert.Equal(t, uint64(890880), RentExemptMinimum(0))
+
+	params := testParams()
+	params.Accounts[0].Lamports = RentExemptMinimum(3)
+
+	// Growing account 0 by 8 bytes needs rent for exactly those bytes.
+	delta, err := params.RentDeltaForRealloc(0, 11)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(8*DefaultLamportsPerByteYear*2), delta)
+
+	// The duplicate at index 2 resolves to account 0.
+	delta, err = params.RentDeltaForRealloc(2, 11)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(8*DefaultLamportsPerByteYear*2), delta)
+
+	// Shrinking, or growing an account holding enough, needs nothing.
+	params.Accounts[0].Lamports = RentExemptMinimum(100)
+	delta, err = params.RentDeltaForRealloc(0, 100)
+	require.NoError(t, err)
+	assert.Zero(t, delta)
+	delta, err = params.RentDeltaForRealloc(0, 50)
+	require.NoError(t, err)
+	assert.Zero(t, delta)
+
+	for _, tc := range []struct {
+		index, newLen int
+	}{
+		{3, 0},
+		{-1, 0},
+		{0, -1},
+		{0, 3 + ReallocSpace + 1},
+		{0, MaxPermittedDataLength + 1},
+	} {
+		_, err := params.RentDeltaForRealloc(tc.index, tc.newLen)
+		assert.Error(t, err, "index %d, length %d", tc.index, tc.newLen)
+	}
+}
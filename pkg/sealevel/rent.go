@@ -0,0 +1,49 @@
+package sealevel
+
+import "fmt"
+
+// Default rent parameters of Solana clusters.
+const (
+	// AccountStorageOverhead is the number of bytes charged for every
+	// account in addition to its data.
+	AccountStorageOverhead = 128
+
+	DefaultLamportsPerByteYear = 3480
+	DefaultExemptionThreshold  = 2.0
+)
+
+// RentExemptMinimum returns the lamport balance an account holding
+// dataLen bytes needs to be rent-exempt under the default rent
+// parameters.
+func RentExemptMinimum(dataLen int) uint64 {
+	bytes := uint64(AccountStorageOverhead + dataLen)
+	return uint64(float64(bytes*DefaultLamportsPerByteYear) * DefaultExemptionThreshold)
+}
+
+// RentDeltaForRealloc returns the lamports the account at accountIndex
+// is missing to remain rent-exempt if its data were resized to newLen,
+// or zero if its balance already suffices. Duplicates resolve to the
+// account they refer to.
+//
+// newLen must not exceed MaxPermittedDataLength, nor grow the data by
+// more than ReallocSpace.
+func (p *Params) RentDeltaForRealloc(accountIndex int, newLen int) (uint64, error) {
+	acc, err := p.account(accountIndex)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case newLen < 0:
+		return 0, fmt.Errorf("account %d: negative data length %d", accountIndex, newLen)
+	case newLen > MaxPermittedDataLength:
+		return 0, fmt.Errorf("account %d data length %d exceeds maximum of %d",
+			accountIndex, newLen, MaxPermittedDataLength)
+	case newLen > acc.dataLen()+ReallocSpace:
+		return 0, fmt.Errorf("account %d data length %d exceeds length %d plus realloc limit",
+			accountIndex, newLen, acc.dataLen())
+	}
+	if min := RentExemptMinimum(newLen); min > acc.Lamports {
+		return min - acc.Lamports, nil
+	}
+	return 0, nil
+}
@@ -12,17 +12,19 @@ type Execution struct {
 	Log Logger
 }
 
-func (t *TxContext) newVMOpts(params *Params) *sbpf.VMOpts {
+func (t *TxContext) newVMOpts(params *Params) (*sbpf.VMOpts, error) {
 	execution := &Execution{
 		Log: new(LogRecorder),
 	}
 	var buf bytes.Buffer
-	params.Serialize(&buf)
+	if err := params.Serialize(&buf); err != nil {
+		return nil, err
+	}
 	return &sbpf.VMOpts{
 		HeapSize: 32 * 1024,
 		Syscalls: registry,
 		Context:  execution,
 		MaxCU:    1_400_000,
 		Input:    buf.Bytes(),
-	}
+	}, nil
 }
@@ -18,11 +18,12 @@ import (
 
 func TestExecute_Memo(t *testing.T) {
 	tx := TxContext{}
-	opts := tx.newVMOpts(&Params{
+	opts, err := tx.newVMOpts(&Params{
 		Accounts:  nil,
 		Data:      []byte("Bla"),
 		ProgramID: [32]byte{},
 	})
+	require.NoError(t, err)
 
 	loader, err := loader.NewLoaderFromBytes(fixtures.Load(t, "sealevel", "MemoSq4gqABAXKb96qnH8TysNcWxMyWCqXgDLGmfcHr.so"))
 	require.NoError(t, err)
@@ -373,7 +374,8 @@ func (e *executeCase) run(t *testing.T) {
 	require.NoError(t, program.Verify())
 
 	tx := TxContext{}
-	opts := tx.newVMOpts(&e.Params)
+	opts, err := tx.newVMOpts(&e.Params)
+	require.NoError(t, err)
 	opts.Tracer = testLogger{t}
 
 	interpreter := sbpf.NewInterpreter(program, opts)
@@ -0,0 +1,52 @@
+//go:build sealevelcompat
+
+// Package sealevelcompat converts the instruction types of
+// github.com/gagliardetto/solana-go to sealevel params.
+//
+// The package is only built with the sealevelcompat build tag, so that
+// the adapter is opted into explicitly:
+//
+//	go build -tags sealevelcompat
+package sealevelcompat
+
+import (
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"go.firedancer.io/radiance/pkg/sealevel"
+)
+
+// FromSolanaGoInstruction returns params invoking ix.
+//
+// Each account meta becomes an account with the signer and writable
+// flags of the meta. Repeated keys become duplicate markers referring
+// to the first occurrence, which is granted the privileges of all
+// occurrences, as done by Params.Canonicalize. Account state such as
+// lamports, owner and data is left for the caller to fill in.
+func FromSolanaGoInstruction(ix solana.Instruction) (*sealevel.Params, error) {
+	data, err := ix.Data()
+	if err != nil {
+		return nil, fmt.Errorf("instruction data: %w", err)
+	}
+	metas := ix.Accounts()
+	accounts := make([]sealevel.AccountParam, len(metas))
+	for i, meta := range metas {
+		if meta == nil {
+			return nil, fmt.Errorf("account meta %d is nil", i)
+		}
+		accounts[i] = sealevel.AccountParam{
+			Key:        meta.PublicKey,
+			IsSigner:   meta.IsSigner,
+			IsWritable: meta.IsWritable,
+		}
+	}
+	p := &sealevel.Params{
+		Accounts:  accounts,
+		Data:      data,
+		ProgramID: ix.ProgramID(),
+	}
+	if err := p.Canonicalize(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,42 @@
+//go:build sealevelcompat
+
+package sealevelcompat
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromSolanaGoInstruction(t *testing.T) {
+	payer := solana.PublicKey{1}
+	dest := solana.PublicKey{2}
+	ix := solana.NewInstruction(
+		solana.SystemProgramID,
+		solana.AccountMetaSlice{
+			solana.NewAccountMeta(payer, true, true),
+			solana.NewAccountMeta(dest, true, false),
+			solana.NewAccountMeta(solana.SysVarRentPubkey, false, false),
+			solana.NewAccountMeta(payer, false, false),
+		},
+		[]byte{2, 0, 0, 0, 0x40, 0x42, 0x0f, 0, 0, 0, 0, 0},
+	)
+
+	p, err := FromSolanaGoInstruction(ix)
+	require.NoError(t, err)
+	require.NoError(t, p.Validate())
+	require.Len(t, p.Accounts, 4)
+	assert.Equal(t, solana.SystemProgramID, p.ProgramID)
+	assert.Equal(t, ix.DataBytes, p.Data)
+
+	assert.Equal(t, payer, p.Accounts[0].Key)
+	assert.True(t, p.Accounts[0].IsSigner)
+	assert.True(t, p.Accounts[0].IsWritable)
+	assert.False(t, p.Accounts[1].IsSigner)
+	assert.True(t, p.Accounts[1].IsWritable)
+	assert.False(t, p.Accounts[2].IsWritable)
+	assert.True(t, p.Accounts[3].IsDuplicate)
+	assert.Equal(t, uint8(0), p.Accounts[3].DuplicateIndex)
+}
@@ -0,0 +1,44 @@
+package sealevel
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"go.firedancer.io/radiance/pkg/base58"
+)
+
+// WriteTSV writes the accounts as tab-separated values, one row per
+// account after a header row.
+//
+// Duplicate accounts only carry the index of the account they refer to.
+func (p *Params) WriteTSV(w io.Writer) error {
+	tw := csv.NewWriter(w)
+	tw.Comma = '\t'
+	_ = tw.Write([]string{
+		"index", "duplicate_of", "key", "owner",
+		"signer", "writable", "executable",
+		"lamports", "rent_epoch", "data_len",
+	})
+	for i := range p.Accounts {
+		acc := &p.Accounts[i]
+		index := strconv.Itoa(i)
+		if acc.IsDuplicate {
+			_ = tw.Write([]string{index, strconv.Itoa(int(acc.DuplicateIndex)), "", "", "", "", "", "", "", ""})
+			continue
+		}
+		_ = tw.Write([]string{
+			index, "",
+			base58.Encode(acc.Key[:]),
+			base58.Encode(acc.Owner[:]),
+			strconv.FormatBool(acc.IsSigner),
+			strconv.FormatBool(acc.IsWritable),
+			strconv.FormatBool(acc.IsExecutable),
+			strconv.FormatUint(acc.Lamports, 10),
+			strconv.FormatUint(acc.RentEpoch, 10),
+			strconv.Itoa(acc.dataLen()),
+		})
+	}
+	tw.Flush()
+	return tw.Error()
+}
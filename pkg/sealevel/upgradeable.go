@@ -0,0 +1,88 @@
+package sealevel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Programs owned by the upgradeable BPF loader (loader-v3) are split
+// across two accounts. The executable program account, whose address is
+// the program ID, only stores the address of its program data account.
+// The program data account, derived from the program ID, holds the
+// deployment slot, the upgrade authority, and the ELF.
+//
+// Neither account is implicitly part of the input region. Instructions
+// operating on such a program pass both as regular accounts, at the
+// positions defined by that instruction. For example, the loader's
+// Upgrade instruction expects the program data account at index 0 and
+// the program account at index 1.
+
+// upgradeableProgramTag is the UpgradeableLoaderState variant of program accounts.
+const upgradeableProgramTag = 2
+
+// ProgramDataAddress returns the address of the program data account
+// belonging to a loader-v3 program.
+func ProgramDataAddress(programID solana.PublicKey) (solana.PublicKey, error) {
+	addr, _, err := solana.FindProgramAddress([][]byte{programID[:]}, solana.BPFLoaderUpgradeableProgramID)
+	return addr, err
+}
+
+// UpgradeableProgramData returns the account data of a loader-v3
+// program account referring to the given program data account.
+func UpgradeableProgramData(programData solana.PublicKey) []byte {
+	data := make([]byte, 4+32)
+	binary.LittleEndian.PutUint32(data, upgradeableProgramTag)
+	copy(data[4:], programData[:])
+	return data
+}
+
+// CheckProgramData verifies that the account at index program is a
+// loader-v3 program account whose program data account is the account
+// at index programData.
+func (p *Params) CheckProgramData(program, programData int) error {
+	prog, err := p.account(program)
+	if err != nil {
+		return err
+	}
+	data, err := p.account(programData)
+	if err != nil {
+		return err
+	}
+
+	if prog.Owner != solana.BPFLoaderUpgradeableProgramID || !prog.IsExecutable {
+		return fmt.Errorf("account %d is not an upgradeable program", program)
+	}
+	wantAddr, err := ProgramDataAddress(prog.Key)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(prog.Data, UpgradeableProgramData(wantAddr)) {
+		return fmt.Errorf("program account %s does not refer to program data %s", prog.Key, wantAddr)
+	}
+	if data.Key != wantAddr {
+		return fmt.Errorf("account %d is %s, expected program data %s", programData, data.Key, wantAddr)
+	}
+	if data.Owner != solana.BPFLoaderUpgradeableProgramID {
+		return fmt.Errorf("program data %s is not owned by the upgradeable loader", data.Key)
+	}
+	return nil
+}
+
+// account returns the account at index i, resolving duplicates.
+func (p *Params) account(i int) (*AccountParam, error) {
+	if i < 0 || i >= len(p.Accounts) {
+		return nil, fmt.Errorf("account index %d out of range", i)
+	}
+	acc := &p.Accounts[i]
+	if acc.IsDuplicate {
+		idx := int(acc.DuplicateIndex)
+		if idx >= len(p.Accounts) || p.Accounts[idx].IsDuplicate {
+			return nil, fmt.Errorf("account %d: invalid duplicate index %d", i, idx)
+		}
+		acc = &p.Accounts[idx]
+	}
+	return acc, nil
+}